@@ -0,0 +1,293 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogFormat determines how each request is written to the AccessLogger.
+type AccessLogFormat uint8
+
+const (
+	// AccessLogCommon writes the NCSA Common Log Format: host ident user [time] "request" status bytes
+	AccessLogCommon AccessLogFormat = iota
+
+	// AccessLogCombined writes the Common Log Format followed by the quoted Referer and User-Agent headers.
+	AccessLogCombined
+
+	// AccessLogJSON writes a JSON object per request containing the configured AccessLogFields.
+	AccessLogJSON
+)
+
+func (this AccessLogFormat) String() string {
+	switch this {
+	case AccessLogCommon:
+		return "common"
+	case AccessLogCombined:
+		return "combined"
+	case AccessLogJSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
+
+type accessLogHandler struct {
+	http.Handler
+	settings atomic.Pointer[accessLogSettings]
+	logger   logger
+}
+type accessLogSettings struct {
+	format        AccessLogFormat
+	fields        []string
+	headers       []string
+	sampleRate    float64
+	slowThreshold time.Duration
+}
+
+func newAccessLogHandler(handler http.Handler, config configuration) *accessLogHandler {
+	this := &accessLogHandler{Handler: handler, logger: config.AccessLogger}
+	this.reload(config)
+	return this
+}
+
+func (this *accessLogHandler) reload(config configuration) {
+	this.settings.Store(&accessLogSettings{
+		format:        config.AccessLogFormat,
+		fields:        config.AccessLogFields,
+		headers:       config.AccessLogHeaders,
+		sampleRate:    config.AccessLogSampleRate,
+		slowThreshold: config.AccessLogSlowThreshold,
+	})
+}
+
+func (this *accessLogHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	entry := accessLogEntry{request: request, started: time.Now()}
+	tracked := newTrackingResponseWriter(response)
+	completed := false
+	defer func() {
+		entry.duration = time.Since(entry.started)
+		entry.status, entry.written = tracked.Status(), tracked.written
+		if !completed {
+			entry.status = 0 // panic not recovered, the connection is aborted by net/http
+		}
+		this.log(entry)
+	}()
+
+	this.Handler.ServeHTTP(tracked.exposed(), request)
+	completed = true
+}
+func (this *accessLogHandler) log(entry accessLogEntry) {
+	settings := this.settings.Load()
+	if !settings.included(entry) {
+		return
+	}
+
+	if structured, ok := this.logger.(*slogLogger); ok {
+		structured.log(entry.level(), "HTTP request", entry.attrs(settings.fields, settings.headers)...)
+		return
+	}
+
+	switch settings.format {
+	case AccessLogJSON:
+		this.logger.Printf("%s", entry.json(settings.fields, settings.headers))
+	case AccessLogCombined:
+		this.logger.Printf("%s", entry.common(true, settings.headers))
+	default:
+		this.logger.Printf("%s", entry.common(false, settings.headers))
+	}
+}
+func (this *accessLogSettings) included(entry accessLogEntry) bool {
+	if entry.status == 0 || entry.status >= http.StatusBadRequest {
+		return true // errors are always logged
+	} else if this.slowThreshold > 0 && entry.duration >= this.slowThreshold {
+		return true // slow requests are always logged
+	} else {
+		return this.sampleRate >= 1 || rand.Float64() < this.sampleRate
+	}
+}
+
+type accessLogEntry struct {
+	request  *http.Request
+	started  time.Time
+	duration time.Duration
+	status   int
+	written  int64
+}
+
+func (this accessLogEntry) common(combined bool, headers []string) string {
+	var builder strings.Builder
+	builder.WriteString(orDash(this.remoteHost()))
+	builder.WriteString(" - ")
+	builder.WriteString(orDash(this.user()))
+	builder.WriteString(this.started.Format(" [02/Jan/2006:15:04:05 -0700] "))
+	builder.WriteString(strconv.Quote(this.request.Method + " " + this.request.RequestURI + " " + this.request.Proto))
+	builder.WriteString(" ")
+	builder.WriteString(strconv.Itoa(this.status))
+	builder.WriteString(" ")
+	if this.written > 0 {
+		builder.WriteString(strconv.FormatInt(this.written, 10))
+	} else {
+		builder.WriteString("-")
+	}
+
+	if combined {
+		builder.WriteString(" " + strconv.Quote(orDash(this.request.Referer())))
+		builder.WriteString(" " + strconv.Quote(orDash(this.request.UserAgent())))
+	}
+
+	for _, name := range headers {
+		builder.WriteString(" " + strconv.Quote(orDash(this.request.Header.Get(name))))
+	}
+
+	if id := RequestIDFromContext(this.request.Context()); len(id) > 0 {
+		builder.WriteString(" " + strconv.Quote(id))
+	}
+
+	return builder.String()
+}
+func (this accessLogEntry) json(fields, headers []string) string {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for _, field := range fields {
+		if builder.Len() > 1 {
+			builder.WriteString(",")
+		}
+		writeJSONField(&builder, field, this.field(field))
+	}
+
+	if id := RequestIDFromContext(this.request.Context()); len(id) > 0 {
+		if builder.Len() > 1 {
+			builder.WriteString(",")
+		}
+		writeJSONField(&builder, "request_id", id)
+	}
+
+	if len(headers) > 0 {
+		captured := make(map[string]string, len(headers))
+		for _, name := range headers {
+			captured[http.CanonicalHeaderKey(name)] = this.request.Header.Get(name)
+		}
+		if builder.Len() > 1 {
+			builder.WriteString(",")
+		}
+		writeJSONField(&builder, "headers", captured)
+	}
+
+	builder.WriteString("}")
+	return builder.String()
+}
+func (this accessLogEntry) attrs(fields, headers []string) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields)+1)
+	for _, field := range fields {
+		if field != AccessLogFieldTime { // every record carries its own time
+			attrs = append(attrs, slog.Any(field, this.field(field)))
+		}
+	}
+
+	if id := RequestIDFromContext(this.request.Context()); len(id) > 0 {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	if len(headers) > 0 {
+		captured := make([]any, 0, len(headers))
+		for _, name := range headers {
+			captured = append(captured, slog.String(http.CanonicalHeaderKey(name), this.request.Header.Get(name)))
+		}
+		attrs = append(attrs, slog.Group("headers", captured...))
+	}
+
+	return attrs
+}
+func (this accessLogEntry) level() slog.Level {
+	if this.status == 0 || this.status >= http.StatusInternalServerError {
+		return slog.LevelError
+	} else if this.status >= http.StatusBadRequest {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+func (this accessLogEntry) field(name string) any {
+	switch name {
+	case AccessLogFieldTime:
+		return this.started.Format(time.RFC3339Nano)
+	case AccessLogFieldRemoteAddress:
+		return this.request.RemoteAddr
+	case AccessLogFieldUser:
+		return this.user()
+	case AccessLogFieldMethod:
+		return this.request.Method
+	case AccessLogFieldURI:
+		return this.request.RequestURI
+	case AccessLogFieldProtocol:
+		return this.request.Proto
+	case AccessLogFieldHost:
+		return this.request.Host
+	case AccessLogFieldStatus:
+		return this.status
+	case AccessLogFieldBytes:
+		return this.written
+	case AccessLogFieldDuration:
+		return this.duration.Seconds()
+	case AccessLogFieldReferer:
+		return this.request.Referer()
+	case AccessLogFieldUserAgent:
+		return this.request.UserAgent()
+	default:
+		return nil
+	}
+}
+func (this accessLogEntry) remoteHost() string {
+	if host, _, err := net.SplitHostPort(this.request.RemoteAddr); err == nil {
+		return host
+	}
+	return this.request.RemoteAddr
+}
+func (this accessLogEntry) user() string {
+	if username, _, ok := this.request.BasicAuth(); ok {
+		return username
+	}
+	return ""
+}
+
+func writeJSONField(builder *strings.Builder, name string, value any) {
+	raw, _ := json.Marshal(value)
+	builder.WriteString(strconv.Quote(name))
+	builder.WriteString(":")
+	builder.Write(raw)
+}
+func orDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
+
+// The names of the fields which may be written using AccessLogJSON.
+const (
+	AccessLogFieldTime          = "time"
+	AccessLogFieldRemoteAddress = "remote_address"
+	AccessLogFieldUser          = "user"
+	AccessLogFieldMethod        = "method"
+	AccessLogFieldURI           = "uri"
+	AccessLogFieldProtocol      = "protocol"
+	AccessLogFieldHost          = "host"
+	AccessLogFieldStatus        = "status"
+	AccessLogFieldBytes         = "bytes"
+	AccessLogFieldDuration      = "duration"
+	AccessLogFieldReferer       = "referer"
+	AccessLogFieldUserAgent     = "user_agent"
+)
+
+var accessLogFields = []string{
+	AccessLogFieldTime, AccessLogFieldRemoteAddress, AccessLogFieldUser, AccessLogFieldMethod, AccessLogFieldURI,
+	AccessLogFieldProtocol, AccessLogFieldHost, AccessLogFieldStatus, AccessLogFieldBytes, AccessLogFieldDuration,
+	AccessLogFieldReferer, AccessLogFieldUserAgent,
+}
@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestAccessLogHandlerFixture(t *testing.T) {
+	gunit.Run(new(AccessLogHandlerFixture), t)
+}
+
+type AccessLogHandlerFixture struct {
+	*gunit.Fixture
+
+	status  int
+	delay   time.Duration
+	handler http.Handler
+	logged  []string
+}
+
+func (this *AccessLogHandlerFixture) Setup() {
+	this.status = http.StatusOK
+}
+func (this *AccessLogHandlerFixture) initialize(options ...option) {
+	var config configuration
+	Options.apply(append([]option{Options.Handler(this), Options.AccessLogger(this)}, options...)...)(&config)
+	this.handler = config.Handler
+}
+func (this *AccessLogHandlerFixture) serve(target string) {
+	request := httptest.NewRequest("GET", target, nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("Referer", "https://referer/")
+	request.Header.Set("User-Agent", "agent/1.0")
+	request.Header.Set("X-Custom", "custom-value")
+	request.SetBasicAuth("user", "password")
+	this.handler.ServeHTTP(httptest.NewRecorder(), request)
+}
+
+func (this *AccessLogHandlerFixture) TestAccessLoggerNotProvided_NotWrapped() {
+	var config configuration
+	Options.apply(Options.Handler(this))(&config)
+
+	this.So(config.AccessLogHandler, should.BeNil)
+}
+func (this *AccessLogHandlerFixture) TestCommonFormat() {
+	this.initialize(Options.AccessLogFormat(AccessLogCommon))
+
+	this.serve("/path?query=1")
+
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.StartWith, "192.0.2.1 - user [")
+	this.So(this.logged[0], should.EndWith, `] "GET /path?query=1 HTTP/1.1" 200 5`)
+}
+func (this *AccessLogHandlerFixture) TestCombinedFormatWithCapturedHeaders() {
+	this.initialize(Options.AccessLogHeaders("X-Custom", "X-Missing"))
+
+	this.serve("/")
+
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.EndWith, `"GET / HTTP/1.1" 200 5 "https://referer/" "agent/1.0" "custom-value" "-"`)
+}
+func (this *AccessLogHandlerFixture) TestJSONFormatWithSelectedFields() {
+	this.initialize(
+		Options.AccessLogFormat(AccessLogJSON),
+		Options.AccessLogFields(AccessLogFieldStatus, AccessLogFieldMethod, AccessLogFieldBytes),
+		Options.AccessLogHeaders("x-custom"),
+	)
+
+	this.serve("/")
+
+	this.So(this.logged, should.Equal, []string{`{"status":200,"method":"GET","bytes":5,"headers":{"X-Custom":"custom-value"}}`})
+	this.So(json.Valid([]byte(this.logged[0])), should.BeTrue)
+}
+func (this *AccessLogHandlerFixture) TestSampling_SuccessfulRequestsOmittedErrorsAndSlowRequestsAlwaysLogged() {
+	this.initialize(Options.AccessLogSampleRate(0), Options.AccessLogSlowThreshold(time.Millisecond*10))
+
+	this.serve("/success")
+	this.status = http.StatusNotFound
+	this.serve("/error")
+	this.status, this.delay = http.StatusOK, time.Millisecond*20
+	this.serve("/slow")
+
+	this.So(this.logged, should.HaveLength, 2)
+	this.So(this.logged[0], should.ContainSubstring, `"GET /error HTTP/1.1" 404`)
+	this.So(this.logged[1], should.ContainSubstring, `"GET /slow HTTP/1.1" 200`)
+}
+func (this *AccessLogHandlerFixture) TestPanicRecovered_LoggedAsInternalServerError() {
+	this.initialize(Options.AccessLogFormat(AccessLogCommon))
+	this.status = -1
+
+	this.serve("/")
+
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.ContainSubstring, `"GET / HTTP/1.1" 500 71`)
+}
+
+func (this *AccessLogHandlerFixture) ServeHTTP(response http.ResponseWriter, _ *http.Request) {
+	if this.status < 0 {
+		panic("boink")
+	}
+	time.Sleep(this.delay)
+	response.WriteHeader(this.status)
+	_, _ = io.WriteString(response, "hello")
+}
+func (this *AccessLogHandlerFixture) Printf(format string, args ...any) {
+	this.logged = append(this.logged, fmt.Sprintf(format, args...))
+}
@@ -4,38 +4,109 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 type configuration struct {
-	Context                  context.Context
-	ContextShutdown          context.CancelFunc
-	Handler                  http.Handler
-	MaxRequestHeaderSize     int
-	ReadRequestTimeout       time.Duration
-	ReadRequestHeaderTimeout time.Duration
-	WriteResponseTimeout     time.Duration
-	IdleConnectionTimeout    time.Duration
-	ShutdownTimeout          time.Duration
-	ForceShutdownTimeout     time.Duration
-	ListenNetwork            string
-	ListenAddress            string
-	ListenConfig             listenConfig
-	ListenAdapter            func(net.Listener) net.Listener
-	ListenReady              func(bool)
-	TLSConfig                *tls.Config
-	HandlePanic              bool
-	DumpRequestOnPanic       bool
-	IgnoredErrors            []error
-	Monitor                  monitor
-	Logger                   logger
-	ErrorLogger              logger
-	HTTPServer               httpServer
+	Context                   context.Context
+	ContextShutdown           context.CancelFunc
+	SoftContext               context.Context // cancelled to begin shutting down gracefully, e.g. by Close
+	SoftShutdown              context.CancelFunc
+	Handler                   http.Handler
+	SwapHandler               *swapHandler
+	MaxRequestHeaderSize      int
+	ReadRequestTimeout        time.Duration
+	ReadRequestHeaderTimeout  time.Duration
+	WriteResponseTimeout      time.Duration
+	IdleConnectionTimeout     time.Duration
+	ShutdownTimeout           time.Duration
+	ForceShutdownTimeout      time.Duration
+	ListenNetwork             string
+	ListenAddress             string
+	ListenAddressError        error
+	ListenBacklog             int
+	ListenKeepAlive           time.Duration
+	ListenKeepAliveInterval   time.Duration
+	ListenKeepAliveCount      int
+	ListenNoDelay             bool
+	ListenFastOpen            int
+	ListenDeferAccept         time.Duration
+	ListenReceiveBuffer       int
+	ListenSendBuffer          int
+	ListenUserTimeout         time.Duration
+	ListenIPv6Only            bool
+	ListenReusePort           bool
+	ListenSocketMode          os.FileMode
+	ListenConfig              listenConfig
+	MaxConnections            int
+	MaxConnectionsPerClient   int
+	ConnectionLimitIPv4Prefix int
+	ConnectionLimitIPv6Prefix int
+	ConnectionLimitBehavior   ConnectionLimitBehavior
+	MaxConnectionAge          time.Duration
+	MaxConnectionAgeJitter    time.Duration
+	MaxConnectionRequests     int
+	ConnectionLifetime        *connectionLifetime
+	MinRequestBodyRate        int
+	MinResponseRate           int
+	MinTransferRateWindow     time.Duration
+	ThroughputHandler         *throughputHandler
+	ListenAdapter             func(net.Listener) net.Listener
+	ListenReady               func(bool)
+	TLSConfig                 *tls.Config
+	HandlePanic               bool
+	DumpRequestOnPanic        bool
+	DumpRedactHeaders         []string
+	DumpAllowHeaders          []string
+	DumpRedactQuery           []string
+	DumpRedactFields          []string
+	DumpMaxBodySize           int
+	CaptureRequestBody        bool
+	IgnoredErrors             []error
+	PanicResponder            PanicResponder
+	PanicClassifier           PanicClassifier
+	PanicDeduplication        time.Duration
+	PanicStormThreshold       int
+	PanicStormInterval        time.Duration
+	PanicStormBehavior        PanicStormBehavior
+	PanicStormHealth          func(bool)
+	RecoveryHandler           *recoveryHandler
+	AccessLogger              logger
+	AccessLogFormat           AccessLogFormat
+	AccessLogFields           []string
+	AccessLogHeaders          []string
+	AccessLogSampleRate       float64
+	AccessLogSlowThreshold    time.Duration
+	AccessLogHandler          *accessLogHandler
+	TraceExporter             SpanExporter
+	TraceSampleRate           float64
+	TracingHandler            *tracingHandler
+	RequestID                 bool
+	RequestIDHeader           string
+	RequestIDMaxLength        int
+	RequestIDGenerator        func() string
+	ReloadOptions             func() []option
+	ReloadSignals             []os.Signal
+	Monitor                   monitor
+	Logger                    logger
+	ErrorLogger               logger
+	ServerLogSuppressed       []ServerLogClass
+	ServerLogRateLimit        int
+	ServerLogRateInterval     time.Duration
+	HTTPServer                httpServer
+	ReloadableHTTPServer      bool
 }
 
 func New(options ...option) ListenCloser {
@@ -49,11 +120,41 @@ var Options singleton
 type singleton struct{}
 type option func(*configuration)
 
+// optionNames returns the names of the options provided (e.g. "HandlePanic" for Options.HandlePanic(false)) such that a
+// setting explicitly provided can be told apart from one which merely holds its zero value.
+func optionNames(options ...option) map[string]bool {
+	names := make(map[string]bool, len(options))
+	for _, item := range options {
+		if item == nil {
+			continue
+		}
+
+		name := runtime.FuncForPC(reflect.ValueOf(item).Pointer()).Name() // e.g. ".../v2.singleton.HandlePanic.func1"
+		if _, name, found := strings.Cut(name, "singleton."); found {
+			name, _, _ = strings.Cut(name, ".")
+			names[name] = true
+		}
+	}
+	return names
+}
+
 func (singleton) Context(value context.Context) option {
 	return func(this *configuration) { this.Context = value }
 }
+
+// ListenAddress accepts a host:port or a URL such as tcp://0.0.0.0:8443 or unix:///run/app.sock with optional query
+// parameters configuring the listener, e.g. tcp://0.0.0.0:8443?tls=/etc/cert.pem,/etc/key.pem&backlog=4096. See
+// applyListenQuery for the parameters supported. An invalid value is reported by Validate and prevents listening. The
+// TLS certificate is loaded once, when the option is first applied.
 func (singleton) ListenAddress(value string) option {
-	return func(this *configuration) { this.ListenNetwork, this.ListenAddress = parseListenAddress(value) }
+	network, address, query, err := parseListenAddress(value)
+	certificate := sync.OnceValues(func() (*tls.Config, error) { return loadListenCertificate(query.Get("tls")) })
+	return func(this *configuration) {
+		this.ListenNetwork, this.ListenAddress, this.ListenAddressError = network, address, err
+		if this.ListenAddressError == nil {
+			this.ListenAddressError = this.applyListenQuery(query, certificate)
+		}
+	}
 }
 func (singleton) TLSConfig(value *tls.Config) option {
 	return func(this *configuration) { this.TLSConfig = value }
@@ -67,9 +168,160 @@ func (singleton) HandlePanic(value bool) option {
 func (singleton) DumpRequestOnPanic(value bool) option {
 	return func(this *configuration) { this.DumpRequestOnPanic = value }
 }
+
+// DumpRedactHeaders replaces the values of the request headers provided with "[REDACTED]" in the request dumped by
+// DumpRequestOnPanic. By default credentials and cookies are redacted (e.g. Authorization, Cookie and X-Api-Key).
+func (singleton) DumpRedactHeaders(value ...string) option {
+	return func(this *configuration) { this.DumpRedactHeaders = value }
+}
+
+// DumpAllowHeaders, when not empty, redacts the value of every request header other than those provided in the request
+// dumped by DumpRequestOnPanic. Headers provided which are also among the DumpRedactHeaders remain redacted.
+func (singleton) DumpAllowHeaders(value ...string) option {
+	return func(this *configuration) { this.DumpAllowHeaders = value }
+}
+
+// DumpRedactQuery replaces the values of the query parameters provided (case-insensitive) in the request dumped by
+// DumpRequestOnPanic. By default common credentials such as "password", "token" and "api_key" are redacted.
+func (singleton) DumpRedactQuery(value ...string) option {
+	return func(this *configuration) { this.DumpRedactQuery = value }
+}
+
+// DumpRedactFields replaces the values of the fields provided (case-insensitive) in a JSON or URL-encoded form body of
+// the request dumped by DumpRequestOnPanic. By default the same names as DumpRedactQuery are redacted.
+func (singleton) DumpRedactFields(value ...string) option {
+	return func(this *configuration) { this.DumpRedactFields = value }
+}
+
+// DumpMaxBodySize limits the number of bytes of the request body dumped by DumpRequestOnPanic, after which a truncation
+// marker is written. Zero is unlimited.
+func (singleton) DumpMaxBodySize(value int) option {
+	return func(this *configuration) { this.DumpMaxBodySize = value }
+}
+
+// CaptureRequestBody retains the request body (up to DumpMaxBodySize) as it's read by the handler such that the body
+// dumped by DumpRequestOnPanic is complete regardless of how much of it was consumed (or whether it was closed) before
+// the panic. A DumpMaxBodySize is required, otherwise at most 64 KiB is retained.
+func (singleton) CaptureRequestBody(value bool) option {
+	return func(this *configuration) { this.CaptureRequestBody = value }
+}
 func (singleton) IgnoredErrors(value ...error) option {
 	return func(this *configuration) { this.IgnoredErrors = value }
 }
+
+// AccessLogger receives a line for each request using the AccessLogFormat configured, nil disables access logging.
+func (singleton) AccessLogger(value logger) option {
+	return func(this *configuration) { this.AccessLogger = value }
+}
+
+// StructuredAccessLogger writes a record for each request to the *slog.Logger provided containing the AccessLogFields
+// and AccessLogHeaders as attributes, the AccessLogFormat has no effect.
+func (singleton) StructuredAccessLogger(value *slog.Logger) option {
+	return func(this *configuration) { this.AccessLogger = newSlogLogger(value) }
+}
+func (singleton) AccessLogFormat(value AccessLogFormat) option {
+	return func(this *configuration) { this.AccessLogFormat = value }
+}
+
+// AccessLogFields determines which fields, and in which order, are written using AccessLogJSON (see AccessLogFieldTime
+// and the related constants). Every field is written by default.
+func (singleton) AccessLogFields(value ...string) option {
+	return func(this *configuration) { this.AccessLogFields = value }
+}
+
+// AccessLogHeaders captures the values of the request headers provided, written as additional quoted values following
+// the Common and Combined formats, or as a "headers" object using AccessLogJSON.
+func (singleton) AccessLogHeaders(value ...string) option {
+	return func(this *configuration) { this.AccessLogHeaders = value }
+}
+
+// AccessLogSampleRate is the fraction (between 0 and 1) of successful requests written to the access log. Requests
+// resulting in an error (a 4xx or 5xx status) or exceeding the AccessLogSlowThreshold are always written.
+func (singleton) AccessLogSampleRate(value float64) option {
+	return func(this *configuration) { this.AccessLogSampleRate = value }
+}
+
+// AccessLogSlowThreshold always writes requests taking at least as long as the value provided regardless of the
+// AccessLogSampleRate, zero disables.
+func (singleton) AccessLogSlowThreshold(value time.Duration) option {
+	return func(this *configuration) { this.AccessLogSlowThreshold = value }
+}
+
+// TraceExporter enables tracing such that each request continues the trace identified by its W3C traceparent header
+// (or starts a new trace) with a server span available through SpanFromContext. Sampled spans are delivered to the
+// exporter provided, nil disables tracing. See InMemoryExporter and OTLPExporter.
+func (singleton) TraceExporter(value SpanExporter) option {
+	return func(this *configuration) { this.TraceExporter = value }
+}
+
+// TraceSampleRate is the fraction (between 0 and 1) of new traces, i.e. those of requests without a traceparent header,
+// which are sampled. Requests continuing a trace follow the sampling decision of their parent.
+func (singleton) TraceSampleRate(value float64) option {
+	return func(this *configuration) { this.TraceSampleRate = value }
+}
+
+// RequestID identifies each request using the value of the RequestIDHeader provided by the client (e.g. a proxy or an
+// upstream service) or, when missing or invalid, a generated ID. The ID is available through RequestIDFromContext, is
+// written to the same header of the response, and is included in the access log and in the log and response of a
+// recovered panic (as the correlation ID).
+func (singleton) RequestID(value bool) option {
+	return func(this *configuration) { this.RequestID = value }
+}
+
+// RequestIDHeader is the name of the request and response header carrying the RequestID, X-Request-ID by default.
+func (singleton) RequestIDHeader(value string) option {
+	return func(this *configuration) { this.RequestIDHeader = value }
+}
+
+// RequestIDMaxLength is the length of the longest ID accepted from the RequestIDHeader, longer IDs are replaced by a
+// generated ID as are those containing characters other than letters, digits and any of "-_.:/+=@".
+func (singleton) RequestIDMaxLength(value int) option {
+	return func(this *configuration) { this.RequestIDMaxLength = value }
+}
+
+// RequestIDGenerator creates the ID of a request lacking a valid RequestIDHeader (e.g. a ULID), by default a version 7
+// UUID. The IDs generated aren't validated.
+func (singleton) RequestIDGenerator(value func() string) option {
+	return func(this *configuration) { this.RequestIDGenerator = value }
+}
+
+// PanicResponder writes the response once a panic has been recovered. By default the body is plain text, an RFC 9457
+// problem details object or an HTML page as negotiated using the Accept header of the request, which is also used when
+// nil is provided.
+func (singleton) PanicResponder(value PanicResponder) option {
+	return func(this *configuration) { this.PanicResponder = value }
+}
+
+// PanicClassifier determines the severity and status of each recovered panic given the request being handled, e.g. to
+// ignore context.Canceled only when the client disconnected. Nil uses the classification determined by default.
+func (singleton) PanicClassifier(value PanicClassifier) option {
+	return func(this *configuration) { this.PanicClassifier = value }
+}
+
+// PanicDeduplication logs only the first occurrence of each distinct stack trace in full within the interval provided,
+// after which a summary of the number of duplicates is written once the interval concludes. Zero disables.
+func (singleton) PanicDeduplication(value time.Duration) option {
+	return func(this *configuration) { this.PanicDeduplication = value }
+}
+
+// PanicStormThreshold enacts the PanicStormBehavior once the number of panics provided are recovered within the
+// interval, summarizing the rate of panics at the end of each interval until it falls below the threshold. Only panics
+// classified as PanicSeverityError are counted. Zero disables.
+func (singleton) PanicStormThreshold(value int, interval time.Duration) option {
+	return func(this *configuration) { this.PanicStormThreshold, this.PanicStormInterval = value, interval }
+}
+
+// PanicStormBehavior determines what happens once the PanicStormThreshold is reached: PanicStormUnhealthy (the default)
+// only reports the server as unhealthy to PanicStormHealth while PanicStormShutdown also shuts the server down.
+func (singleton) PanicStormBehavior(value PanicStormBehavior) option {
+	return func(this *configuration) { this.PanicStormBehavior = value }
+}
+
+// PanicStormHealth is called with false once the PanicStormThreshold is reached and with true once the rate of panics
+// falls below the threshold, e.g. to fail a readiness probe such that traffic is routed elsewhere.
+func (singleton) PanicStormHealth(value func(healthy bool)) option {
+	return func(this *configuration) { this.PanicStormHealth = value }
+}
 func (singleton) HTTPServer(value httpServer) option {
 	return func(this *configuration) { this.HTTPServer = value }
 }
@@ -94,15 +346,160 @@ func (singleton) ShutdownTimeout(value time.Duration) option {
 func (singleton) ForceShutdownTimeout(value time.Duration) option {
 	return func(this *configuration) { this.ForceShutdownTimeout = value }
 }
+
+// ListenBacklog sets the maximum length of the queue of pending connections, zero uses the system default. Listening
+// fails on Windows unless zero.
+func (singleton) ListenBacklog(value int) option {
+	return func(this *configuration) { this.ListenBacklog = value }
+}
+
+// ListenKeepAlive sets the idle time before TCP keep-alive probes are sent on accepted connections, zero uses the Go
+// default and a negative value disables keep-alive.
+func (singleton) ListenKeepAlive(value time.Duration) option {
+	return func(this *configuration) { this.ListenKeepAlive = value }
+}
+
+// ListenKeepAliveInterval sets the time between TCP keep-alive probes, zero uses the Go default.
+func (singleton) ListenKeepAliveInterval(value time.Duration) option {
+	return func(this *configuration) { this.ListenKeepAliveInterval = value }
+}
+
+// ListenKeepAliveCount sets the number of unanswered TCP keep-alive probes before a connection is dropped, zero uses the
+// Go default.
+func (singleton) ListenKeepAliveCount(value int) option {
+	return func(this *configuration) { this.ListenKeepAliveCount = value }
+}
+
+// ListenNoDelay indicates whether Nagle's algorithm is disabled (TCP_NODELAY) on accepted connections, as is the Go
+// default.
+func (singleton) ListenNoDelay(value bool) option {
+	return func(this *configuration) { this.ListenNoDelay = value }
+}
+
+// ListenFastOpen enables TCP_FASTOPEN with the queue length provided, zero leaves it disabled. Linux only, listening
+// fails elsewhere unless zero.
+func (singleton) ListenFastOpen(value int) option {
+	return func(this *configuration) { this.ListenFastOpen = value }
+}
+
+// ListenDeferAccept enables TCP_DEFER_ACCEPT such that connections are only accepted once data arrives or the duration
+// provided (rounded up to whole seconds) elapses, zero leaves it disabled. Linux only, listening fails elsewhere unless
+// zero.
+func (singleton) ListenDeferAccept(value time.Duration) option {
+	return func(this *configuration) { this.ListenDeferAccept = value }
+}
+
+// ListenReceiveBuffer sets SO_RCVBUF in bytes, zero uses the system default.
+func (singleton) ListenReceiveBuffer(value int) option {
+	return func(this *configuration) { this.ListenReceiveBuffer = value }
+}
+
+// ListenSendBuffer sets SO_SNDBUF in bytes, zero uses the system default.
+func (singleton) ListenSendBuffer(value int) option {
+	return func(this *configuration) { this.ListenSendBuffer = value }
+}
+
+// ListenUserTimeout sets TCP_USER_TIMEOUT, the maximum time transmitted data may remain unacknowledged before the
+// connection is closed, zero uses the system default. Linux only, listening fails elsewhere unless zero.
+func (singleton) ListenUserTimeout(value time.Duration) option {
+	return func(this *configuration) { this.ListenUserTimeout = value }
+}
+
+// ListenIPv6Only sets IPV6_V6ONLY on IPv6 listeners such that IPv4 connections are not accepted.
+func (singleton) ListenIPv6Only(value bool) option {
+	return func(this *configuration) { this.ListenIPv6Only = value }
+}
+
+// ListenReusePort indicates whether the listening socket is bound with SO_REUSEPORT.
+func (singleton) ListenReusePort(value bool) option {
+	return func(this *configuration) { this.ListenReusePort = value }
+}
+
+// ListenSocketMode sets the file mode of a UNIX domain socket once bound, zero leaves the mode unchanged.
+func (singleton) ListenSocketMode(value os.FileMode) option {
+	return func(this *configuration) { this.ListenSocketMode = value }
+}
+
+// ListenConfig replaces the default listen configuration, in which case none of the other Listen* socket settings (e.g.
+// ListenBacklog, ListenKeepAlive, ListenReusePort) have any effect.
 func (singleton) ListenConfig(value listenConfig) option {
 	return func(this *configuration) { this.ListenConfig = value }
 }
+
+// MaxConnections limits the number of concurrent connections, zero is unlimited. See ConnectionLimitBehavior.
+func (singleton) MaxConnections(value int) option {
+	return func(this *configuration) { this.MaxConnections = value }
+}
+
+// MaxConnectionsPerClient limits the number of concurrent connections from a single client network (see
+// ConnectionLimitPrefix), zero is unlimited. See ConnectionLimitBehavior.
+func (singleton) MaxConnectionsPerClient(value int) option {
+	return func(this *configuration) { this.MaxConnectionsPerClient = value }
+}
+
+// ConnectionLimitPrefix sets the size of the CIDR prefix of the remote addresses which are considered to belong to the
+// same client for purposes of MaxConnectionsPerClient, by default 32 (IPv4) and 128 (IPv6), i.e. a single IP address.
+func (singleton) ConnectionLimitPrefix(ipv4, ipv6 int) option {
+	return func(this *configuration) { this.ConnectionLimitIPv4Prefix, this.ConnectionLimitIPv6Prefix = ipv4, ipv6 }
+}
+func (singleton) ConnectionLimitBehavior(value ConnectionLimitBehavior) option {
+	return func(this *configuration) { this.ConnectionLimitBehavior = value }
+}
+
+// MaxConnectionAge limits how long a connection is reused, zero is unlimited. Once reached, the connection is closed
+// when idle or after the response being written (GOAWAY for HTTP/2). Combined with a load balancer operating at the
+// transport layer (L4) this allows clients to be rebalanced across instances.
+func (singleton) MaxConnectionAge(value time.Duration) option {
+	return func(this *configuration) { this.MaxConnectionAge = value }
+}
+
+// MaxConnectionAgeJitter adds a random duration up to the value provided to the MaxConnectionAge of each connection
+// such that connections established at the same time are not all closed at the same time.
+func (singleton) MaxConnectionAgeJitter(value time.Duration) option {
+	return func(this *configuration) { this.MaxConnectionAgeJitter = value }
+}
+
+// MaxConnectionRequests limits the number of requests served by a connection, zero is unlimited.
+func (singleton) MaxConnectionRequests(value int) option {
+	return func(this *configuration) { this.MaxConnectionRequests = value }
+}
+
+// MinRequestBodyRate aborts a connection reading the request body slower than the bytes per second provided, zero
+// disables. Only time spent waiting on the client counts toward the rate and ReadRequestTimeout no longer applies to
+// the request body such that large uploads from fast clients aren't cut off. The rate is measured across the requests
+// of a keep-alive connection, excluding the time it spends idle between requests.
+func (singleton) MinRequestBodyRate(value int) option {
+	return func(this *configuration) { this.MinRequestBodyRate = value }
+}
+
+// MinResponseRate aborts a connection accepting the response slower than the bytes per second provided, zero disables.
+// Only time spent waiting on the client counts toward the rate and WriteResponseTimeout no longer applies to the
+// response such that large downloads to fast clients aren't cut off.
+func (singleton) MinResponseRate(value int) option {
+	return func(this *configuration) { this.MinResponseRate = value }
+}
+
+// MinTransferRateWindow is the sliding window over which MinRequestBodyRate and MinResponseRate are measured. A
+// connection is never aborted before having kept the server waiting for at least this long. Windows shorter than 10ms
+// are raised to 10ms.
+func (singleton) MinTransferRateWindow(value time.Duration) option {
+	return func(this *configuration) { this.MinTransferRateWindow = value }
+}
 func (singleton) ListenAdapter(value func(net.Listener) net.Listener) option {
 	return func(this *configuration) { this.ListenAdapter = value }
 }
 func (singleton) ListenReady(value func(bool)) option {
 	return func(this *configuration) { this.ListenReady = value }
 }
+
+// ReloadOnSignal reloads the server settings using the options returned by the callback provided each time one of the
+// signals is received (SIGHUP if no signals are specified). See Reloader for the settings which may be reloaded.
+func (singleton) ReloadOnSignal(value func() []option, signals ...os.Signal) option {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	return func(this *configuration) { this.ReloadOptions, this.ReloadSignals = value, signals }
+}
 func (singleton) Monitor(value monitor) option {
 	return func(this *configuration) { this.Monitor = value }
 }
@@ -113,39 +510,93 @@ func (singleton) ErrorLogger(value logger) option {
 	return func(this *configuration) { this.ErrorLogger = value }
 }
 
+// SuppressServerLog discards the classes of messages provided which are written by the http.Server to its ErrorLog, in
+// addition to ServerLogQuerySemicolon which is always suppressed (see golang.org/issue/25192).
+func (singleton) SuppressServerLog(value ...ServerLogClass) option {
+	return func(this *configuration) {
+		for _, class := range value {
+			if !slices.Contains(this.ServerLogSuppressed, class) {
+				this.ServerLogSuppressed = append(this.ServerLogSuppressed, class)
+			}
+		}
+	}
+}
+
+// ServerLogRateLimit writes at most the number of messages of each class provided per interval to the ErrorLog, after
+// which a summary of the number of messages suppressed is written once the interval concludes. Zero is unlimited.
+func (singleton) ServerLogRateLimit(value int, interval time.Duration) option {
+	return func(this *configuration) { this.ServerLogRateLimit, this.ServerLogRateInterval = value, interval }
+}
+
+// StructuredLogger uses the *slog.Logger provided as both the Logger and the ErrorLogger such that lifecycle events,
+// messages from the http.Server and recovered panics are written as records with levels and attributes. A nil
+// *slog.Logger discards everything.
+func (singleton) StructuredLogger(value *slog.Logger) option {
+	return func(this *configuration) {
+		var target logger = &nop{}
+		if value != nil {
+			target = newSlogLogger(value)
+		}
+		this.Logger, this.ErrorLogger = target, target
+	}
+}
+
 func (singleton) apply(options ...option) option {
 	return func(this *configuration) {
 		for _, item := range Options.defaults(options...) {
 			item(this)
 		}
 
+		this.Context, this.ContextShutdown = context.WithCancel(this.Context)
+		this.SoftContext, this.SoftShutdown = context.WithCancel(this.Context)
+
+		this.SwapHandler = newSwapHandler(this.Handler)
+		this.Handler = this.SwapHandler
+
 		if this.HandlePanic {
-			this.Handler = newRecoveryHandler(this.Handler, this.IgnoredErrors, this.DumpRequestOnPanic, this.Monitor, this.Logger)
+			this.RecoveryHandler = newRecoveryHandler(this.Handler, *this)
+			this.Handler = this.RecoveryHandler
+		}
+
+		if this.TraceExporter != nil {
+			this.TracingHandler = newTracingHandler(this.Handler, *this)
+			this.Handler = this.TracingHandler
+		}
+
+		if this.AccessLogger != nil {
+			this.AccessLogHandler = newAccessLogHandler(this.Handler, *this)
+			this.Handler = this.AccessLogHandler
+		}
+
+		if this.RequestID {
+			this.Handler = newRequestIDHandler(this.Handler, *this)
+		}
+
+		if this.MinRequestBodyRate > 0 || this.MinResponseRate > 0 {
+			this.ThroughputHandler = newThroughputHandler(this.Handler, this.MinRequestBodyRate, this.MinResponseRate, this.MinTransferRateWindow, this.Monitor, this.Logger)
+			this.Handler = this.ThroughputHandler
+		}
+
+		if this.MaxConnectionAge > 0 || this.MaxConnectionRequests > 0 {
+			this.ConnectionLifetime = newConnectionLifetime(this.Handler, this.MaxConnectionAge, this.MaxConnectionAgeJitter, this.MaxConnectionRequests)
+			this.Handler = this.ConnectionLifetime
+		}
+
+		if monitor, ok := this.Monitor.(requestMonitor); ok {
+			this.Handler = newTelemetryHandler(this.Handler, monitor)
+		}
+
+		if this.ListenConfig == nil {
+			this.ListenConfig = newSocketListenConfig(*this)
 		}
 
-		this.Context, this.ContextShutdown = context.WithCancel(this.Context)
 		if this.HTTPServer == nil {
-			this.HTTPServer = &http.Server{
-				Addr:              this.ListenAddress,
-				Handler:           this.Handler,
-				MaxHeaderBytes:    this.MaxRequestHeaderSize,
-				ReadTimeout:       this.ReadRequestTimeout,
-				ReadHeaderTimeout: this.ReadRequestHeaderTimeout,
-				WriteTimeout:      this.WriteResponseTimeout,
-				IdleTimeout:       this.IdleConnectionTimeout,
-				BaseContext:       func(net.Listener) context.Context { return this.Context },
-				ErrorLog:          newServerLogger(this.ErrorLogger),
-			}
+			this.HTTPServer = newHTTPServer(*this)
+			this.ReloadableHTTPServer = true
 		}
 	}
 }
 func (singleton) defaults(options ...option) []option {
-	var defaultListenConfig = &net.ListenConfig{Control: func(_, _ string, conn syscall.RawConn) error {
-		return conn.Control(func(descriptor uintptr) {
-			_ = syscall.SetsockoptInt(int(descriptor), syscall.SOL_SOCKET, socketReusePort, 1)
-		})
-	}}
-
 	defaultNop := &nop{}
 
 	return append([]option{
@@ -160,26 +611,140 @@ func (singleton) defaults(options ...option) []option {
 		Options.ForceShutdownTimeout(time.Second),
 		Options.HandlePanic(true),
 		Options.DumpRequestOnPanic(false),
+		Options.DumpRedactHeaders(defaultRedactedHeaders...),
+		Options.DumpAllowHeaders(),
+		Options.DumpRedactQuery(defaultRedactedFields...),
+		Options.DumpRedactFields(defaultRedactedFields...),
+		Options.DumpMaxBodySize(1024 * 4),
+		Options.CaptureRequestBody(false),
 		Options.IgnoredErrors(context.Canceled, context.DeadlineExceeded, sql.ErrTxDone),
+		Options.PanicResponder(defaultPanicResponder),
+		Options.PanicClassifier(nil),
+		Options.PanicDeduplication(0),
+		Options.PanicStormThreshold(0, time.Minute),
+		Options.PanicStormBehavior(PanicStormUnhealthy),
+		Options.PanicStormHealth(nil),
+		Options.TraceExporter(nil),
+		Options.RequestID(false),
+		Options.RequestIDHeader("X-Request-ID"),
+		Options.RequestIDMaxLength(128),
+		Options.RequestIDGenerator(nil),
+		Options.AccessLogger(nil),
+		Options.AccessLogFormat(AccessLogCombined),
+		Options.AccessLogFields(accessLogFields...),
+		Options.AccessLogHeaders(),
+		Options.AccessLogSampleRate(1),
+		Options.AccessLogSlowThreshold(0),
+		Options.TraceSampleRate(1),
 		Options.Context(context.Background()),
 		Options.Handler(defaultNop),
 		Options.Monitor(defaultNop),
 		Options.Logger(defaultNop),
 		Options.ErrorLogger(defaultNop),
-		Options.ListenConfig(defaultListenConfig),
+		Options.SuppressServerLog(ServerLogQuerySemicolon),
+		Options.ServerLogRateLimit(0, time.Minute),
+		Options.ListenBacklog(0),
+		Options.ListenKeepAlive(0),
+		Options.ListenKeepAliveInterval(0),
+		Options.ListenKeepAliveCount(0),
+		Options.ListenNoDelay(true),
+		Options.ListenFastOpen(0),
+		Options.ListenDeferAccept(0),
+		Options.ListenReceiveBuffer(0),
+		Options.ListenSendBuffer(0),
+		Options.ListenUserTimeout(0),
+		Options.ListenIPv6Only(false),
+		Options.ListenReusePort(true),
+		Options.ListenSocketMode(0),
+		Options.ListenConfig(nil),
+		Options.MaxConnections(0),
+		Options.MaxConnectionsPerClient(0),
+		Options.ConnectionLimitPrefix(32, 128),
+		Options.ConnectionLimitBehavior(ConnectionLimitBlock),
+		Options.MaxConnectionAge(0),
+		Options.MaxConnectionAgeJitter(0),
+		Options.MaxConnectionRequests(0),
+		Options.MinRequestBodyRate(0),
+		Options.MinResponseRate(0),
+		Options.MinTransferRateWindow(time.Second * 10),
 		Options.ListenAdapter(nil),
 		Options.ListenReady(nil),
+		Options.ReloadOnSignal(nil),
 	}, options...)
 }
 
-func parseListenAddress(value string) (network, address string) {
-	if parsed := parseURL(value); parsed == nil {
-		return "tcp", value
-	} else if strings.ToLower(parsed.Scheme) == "unix" {
-		return "unix", value[len("unix://"):] // don't prepend slash which assumes full path because path might be relative
-	} else {
-		return coalesce(parsed.Scheme, "tcp"), coalesce(parsed.Host, parsed.Path)
+func newHTTPServer(config configuration) *http.Server {
+	server := &http.Server{
+		Addr:              config.ListenAddress,
+		Handler:           config.Handler,
+		MaxHeaderBytes:    config.MaxRequestHeaderSize,
+		ReadTimeout:       config.ReadRequestTimeout,
+		ReadHeaderTimeout: config.ReadRequestHeaderTimeout,
+		WriteTimeout:      config.WriteResponseTimeout,
+		IdleTimeout:       config.IdleConnectionTimeout,
+		BaseContext:       func(net.Listener) context.Context { return config.Context },
+		ErrorLog:          newServerLogger(config),
+	}
+
+	if config.ThroughputHandler != nil {
+		server.ConnContext = config.ThroughputHandler.ConnContext
+	}
+
+	if config.ConnectionLifetime != nil {
+		throughput := server.ConnContext
+		server.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+			if throughput != nil {
+				ctx = throughput(ctx, conn)
+			}
+			return config.ConnectionLifetime.ConnContext(ctx, conn)
+		}
+		server.ConnState = config.ConnectionLifetime.ConnState
+	}
+
+	if monitor, ok := config.Monitor.(connectionStateMonitor); ok {
+		lifetime := server.ConnState
+		server.ConnState = func(conn net.Conn, state http.ConnState) {
+			if lifetime != nil {
+				lifetime(conn, state)
+			}
+			monitor.ConnectionStateChanged(conn, state)
+		}
+	}
+
+	return server
+}
+
+func parseListenAddress(value string) (network, address string, query url.Values, err error) {
+	raw := value
+	value, rawQuery, _ := strings.Cut(value, "?")
+	if query, err = url.ParseQuery(rawQuery); err != nil {
+		err = fmt.Errorf("%w: ListenAddress query [%s]: %w", ErrInvalidSetting, rawQuery, err)
+	}
+
+	parsed := parseURL(value)
+	switch {
+	case parsed == nil:
+		network, address = "tcp", value
+	case len(parsed.Opaque) > 0: // e.g. localhost:8080 is read as the scheme "localhost"
+		return "", "", query, fmt.Errorf("%w: ListenAddress [%s]: expected host:port or a URL such as tcp://host:port", ErrInvalidSetting, raw)
+	case parsed.Scheme == "unix" || parsed.Scheme == "unixpacket":
+		prefix := parsed.Scheme + "://"
+		if !strings.HasPrefix(strings.ToLower(value), prefix) {
+			return "", "", query, fmt.Errorf("%w: ListenAddress [%s]: expected a URL such as %s/run/app.sock", ErrInvalidSetting, raw, prefix)
+		}
+		network, address = parsed.Scheme, value[len(prefix):] // don't prepend slash which assumes full path because path might be relative
+	case len(parsed.Scheme) == 0:
+		network, address = "tcp", coalesce(parsed.Host, parsed.Path)
+	case parsed.User != nil || len(parsed.Fragment) > 0 || len(strings.Trim(parsed.Path, "/")) > 0:
+		return "", "", query, fmt.Errorf("%w: ListenAddress [%s]: unexpected credentials, path or fragment", ErrInvalidSetting, raw)
+	default:
+		network, address = parsed.Scheme, parsed.Host
+	}
+
+	if len(address) == 0 && len(raw) > 0 {
+		return "", "", query, fmt.Errorf("%w: ListenAddress [%s]: missing host:port or socket path", ErrInvalidSetting, raw)
 	}
+	return network, address, query, err
 }
 func parseURL(value string) *url.URL {
 	value = strings.TrimSpace(value)
@@ -0,0 +1,418 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadEnvironment maps environment variables having the prefix provided onto options, e.g. with a prefix of "HTTP_"
+// the variable HTTP_READ_REQUEST_TIMEOUT=10s becomes Options.ReadRequestTimeout(time.Second*10). As with the keys of
+// LoadFile, variables having the prefix which don't correspond to a setting are rejected, so the prefix shouldn't be
+// shared with unrelated variables (e.g. HTTP_PROXY). The options returned compose with those provided explicitly to New
+// where options later in the list take precedence over earlier ones.
+func LoadEnvironment(prefix string) ([]option, error) {
+	values := make(map[string]string)
+	for _, item := range os.Environ() {
+		if name, value, _ := strings.Cut(item, "="); strings.HasPrefix(name, prefix) {
+			values[normalizeSettingName(name[len(prefix):])] = value
+		}
+	}
+
+	return loadOptions(values)
+}
+
+// LoadFile maps the settings in the file provided onto options. Files with a ".json" extension must contain a single
+// object, all other files are read as flat "key = value" (TOML-style) or "key: value" (YAML-style) lines with blank
+// lines and lines starting with '#' ignored. Keys are case-insensitive and may be written as ListenAddress,
+// listen_address or listen-address. Unknown keys are rejected.
+func LoadFile(path string) ([]option, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		values, err = parseJSONSettings(raw)
+	} else {
+		values, err = parseFlatSettings(raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidSetting, path, err)
+	}
+
+	return loadOptions(values)
+}
+
+func parseJSONSettings(raw []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var document map[string]any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(document))
+	for name, value := range document {
+		switch typed := value.(type) {
+		case []any:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[normalizeSettingName(name)] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("nested value for [%s] not supported", name)
+		case nil:
+			continue
+		default:
+			values[normalizeSettingName(name)] = fmt.Sprint(typed)
+		}
+	}
+	return values, nil
+}
+func parseFlatSettings(raw []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' || text == "---" {
+			continue
+		}
+
+		index := strings.IndexAny(text, "=:")
+		if index < 0 {
+			return nil, fmt.Errorf("line %d: expected [key = value] or [key: value]", line)
+		}
+
+		values[normalizeSettingName(text[:index])] = unquote(strings.TrimSpace(text[index+1:]))
+	}
+	return values, scanner.Err()
+}
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+func normalizeSettingName(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(value)
+}
+
+func loadOptions(values map[string]string) (options []option, err error) {
+	var errs []error
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic errors and option order
+
+	for _, name := range names {
+		if isTLSSetting(name) {
+			continue
+		} else if parser := settingParsers[name]; parser == nil {
+			errs = append(errs, fmt.Errorf("%w: unknown setting [%s]", ErrInvalidSetting, name))
+		} else if item, err := parser(values[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%w: [%s]: %w", ErrInvalidSetting, name, err))
+		} else {
+			options = append(options, item)
+		}
+	}
+
+	if item, err := loadTLSOptions(values); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidSetting, err))
+	} else if item != nil {
+		options = append(options, item)
+	}
+
+	return options, errors.Join(errs...)
+}
+
+var settingParsers = map[string]func(string) (option, error){
+	"listenaddress":            parseListenAddressSetting,
+	"listenbacklog":            parseIntSetting(Options.ListenBacklog),
+	"listenkeepalive":          parseDurationSetting(Options.ListenKeepAlive),
+	"listenkeepaliveinterval":  parseDurationSetting(Options.ListenKeepAliveInterval),
+	"listenkeepalivecount":     parseIntSetting(Options.ListenKeepAliveCount),
+	"listennodelay":            parseBoolSetting(Options.ListenNoDelay),
+	"listenfastopen":           parseIntSetting(Options.ListenFastOpen),
+	"listendeferaccept":        parseDurationSetting(Options.ListenDeferAccept),
+	"listenreceivebuffer":      parseIntSetting(Options.ListenReceiveBuffer),
+	"listensendbuffer":         parseIntSetting(Options.ListenSendBuffer),
+	"listenusertimeout":        parseDurationSetting(Options.ListenUserTimeout),
+	"listenipv6only":           parseBoolSetting(Options.ListenIPv6Only),
+	"listenreuseport":          parseBoolSetting(Options.ListenReusePort),
+	"listensocketmode":         parseSocketModeSetting,
+	"maxrequestheadersize":     parseIntSetting(Options.MaxRequestHeaderSize),
+	"maxconnections":           parseIntSetting(Options.MaxConnections),
+	"maxconnectionsperclient":  parseIntSetting(Options.MaxConnectionsPerClient),
+	"connectionlimitprefix":    parseConnectionLimitPrefix,
+	"connectionlimitbehavior":  parseConnectionLimitBehavior,
+	"maxconnectionage":         parseDurationSetting(Options.MaxConnectionAge),
+	"maxconnectionagejitter":   parseDurationSetting(Options.MaxConnectionAgeJitter),
+	"maxconnectionrequests":    parseIntSetting(Options.MaxConnectionRequests),
+	"minrequestbodyrate":       parseIntSetting(Options.MinRequestBodyRate),
+	"minresponserate":          parseIntSetting(Options.MinResponseRate),
+	"mintransferratewindow":    parseDurationSetting(Options.MinTransferRateWindow),
+	"readrequesttimeout":       parseDurationSetting(Options.ReadRequestTimeout),
+	"readrequestheadertimeout": parseDurationSetting(Options.ReadRequestHeaderTimeout),
+	"writeresponsetimeout":     parseDurationSetting(Options.WriteResponseTimeout),
+	"idleconnectiontimeout":    parseDurationSetting(Options.IdleConnectionTimeout),
+	"shutdowntimeout":          parseDurationSetting(Options.ShutdownTimeout),
+	"forceshutdowntimeout":     parseDurationSetting(Options.ForceShutdownTimeout),
+	"handlepanic":              parseBoolSetting(Options.HandlePanic),
+	"dumprequestonpanic":       parseBoolSetting(Options.DumpRequestOnPanic),
+	"dumpredactheaders":        parseListSetting(Options.DumpRedactHeaders),
+	"dumpallowheaders":         parseListSetting(Options.DumpAllowHeaders),
+	"dumpredactquery":          parseListSetting(Options.DumpRedactQuery),
+	"dumpredactfields":         parseListSetting(Options.DumpRedactFields),
+	"dumpmaxbodysize":          parseIntSetting(Options.DumpMaxBodySize),
+	"capturerequestbody":       parseBoolSetting(Options.CaptureRequestBody),
+	"ignorederrors":            parseIgnoredErrors,
+	"suppressserverlog":        parseServerLogClasses,
+	"serverlogratelimit":       parseRateSetting(Options.ServerLogRateLimit),
+	"panicdeduplication":       parseDurationSetting(Options.PanicDeduplication),
+	"panicstormthreshold":      parseRateSetting(Options.PanicStormThreshold),
+	"panicstormbehavior":       parsePanicStormBehavior,
+	"accesslogformat":          parseAccessLogFormat,
+	"accesslogfields":          parseListSetting(Options.AccessLogFields),
+	"accesslogheaders":         parseListSetting(Options.AccessLogHeaders),
+	"accesslogsamplerate":      parseFloatSetting(Options.AccessLogSampleRate),
+	"accesslogslowthreshold":   parseDurationSetting(Options.AccessLogSlowThreshold),
+	"tracesamplerate":          parseFloatSetting(Options.TraceSampleRate),
+	"requestid":                parseBoolSetting(Options.RequestID),
+	"requestidheader":          parseStringSetting(Options.RequestIDHeader),
+	"requestidmaxlength":       parseIntSetting(Options.RequestIDMaxLength),
+}
+
+func parseListenAddressSetting(value string) (option, error) {
+	var probe configuration
+	item := Options.ListenAddress(value)
+	item(&probe)
+	return item, probe.ListenAddressError
+}
+func parseStringSetting(target func(string) option) func(string) (option, error) {
+	return func(value string) (option, error) { return target(strings.TrimSpace(value)), nil }
+}
+func parseIntSetting(target func(int) option) func(string) (option, error) {
+	return func(value string) (option, error) {
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		return target(parsed), err
+	}
+}
+func parseDurationSetting(target func(time.Duration) option) func(string) (option, error) {
+	return func(value string) (option, error) {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		return target(parsed), err
+	}
+}
+func parseFloatSetting(target func(float64) option) func(string) (option, error) {
+	return func(value string) (option, error) {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return target(parsed), err
+	}
+}
+func parseBoolSetting(target func(bool) option) func(string) (option, error) {
+	return func(value string) (option, error) {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		return target(parsed), err
+	}
+}
+func parseSocketModeSetting(value string) (option, error) {
+	parsed, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+	return Options.ListenSocketMode(os.FileMode(parsed) & os.ModePerm), err
+}
+func parseConnectionLimitPrefix(value string) (option, error) { // "24,64" (IPv4, IPv6)
+	rawIPv4, rawIPv6, found := strings.Cut(value, ",")
+	if !found {
+		return nil, fmt.Errorf("expected prefixes for IPv4 and IPv6 separated by a comma, e.g. [24,64], got [%s]", value)
+	}
+	ipv4, err := strconv.Atoi(strings.TrimSpace(rawIPv4))
+	if err != nil {
+		return nil, err
+	}
+	ipv6, err := strconv.Atoi(strings.TrimSpace(rawIPv6))
+	if err != nil {
+		return nil, err
+	}
+	return Options.ConnectionLimitPrefix(ipv4, ipv6), nil
+}
+func parseConnectionLimitBehavior(value string) (option, error) {
+	for _, behavior := range []ConnectionLimitBehavior{ConnectionLimitBlock, ConnectionLimitClose, ConnectionLimitServiceUnavailable} {
+		if strings.EqualFold(strings.TrimSpace(value), behavior.String()) {
+			return Options.ConnectionLimitBehavior(behavior), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown behavior [%s], expected [block], [close] or [503]", value)
+}
+func parseAccessLogFormat(value string) (option, error) {
+	for _, format := range []AccessLogFormat{AccessLogCommon, AccessLogCombined, AccessLogJSON} {
+		if strings.EqualFold(strings.TrimSpace(value), format.String()) {
+			return Options.AccessLogFormat(format), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown format [%s], expected [common], [combined] or [json]", value)
+}
+func parseServerLogClasses(value string) (option, error) {
+	var classes []ServerLogClass
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); len(name) == 0 {
+			continue
+		} else if class := slices.IndexFunc(serverLogClasses, func(class ServerLogClass) bool { return strings.EqualFold(name, class.String()) }); class < 0 {
+			return nil, fmt.Errorf("unknown server log class [%s]", name)
+		} else {
+			classes = append(classes, serverLogClasses[class])
+		}
+	}
+	return Options.SuppressServerLog(classes...), nil
+}
+func parseRateSetting(target func(int, time.Duration) option) func(string) (option, error) {
+	return func(value string) (option, error) { // "10/1m" (per minute when no interval is specified)
+		rawLimit, rawInterval, found := strings.Cut(value, "/")
+		limit, err := strconv.Atoi(strings.TrimSpace(rawLimit))
+		if err != nil {
+			return nil, err
+		}
+
+		interval := time.Minute
+		if found {
+			if interval, err = time.ParseDuration(strings.TrimSpace(rawInterval)); err != nil {
+				return nil, err
+			}
+		}
+
+		return target(limit, interval), nil
+	}
+}
+func parsePanicStormBehavior(value string) (option, error) {
+	for _, behavior := range []PanicStormBehavior{PanicStormUnhealthy, PanicStormShutdown} {
+		if strings.EqualFold(strings.TrimSpace(value), behavior.String()) {
+			return Options.PanicStormBehavior(behavior), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown behavior [%s], expected [unhealthy] or [shutdown]", value)
+}
+func parseListSetting(target func(...string) option) func(string) (option, error) {
+	return func(value string) (option, error) {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+		return target(items...), nil
+	}
+}
+func parseIgnoredErrors(value string) (option, error) {
+	var ignored []error
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); len(name) == 0 {
+			continue
+		} else if err, found := namedErrors[name]; !found {
+			return nil, fmt.Errorf("unknown error [%s]", name)
+		} else {
+			ignored = append(ignored, err)
+		}
+	}
+	return Options.IgnoredErrors(ignored...), nil
+}
+
+var namedErrors = map[string]error{
+	"context.Canceled":         context.Canceled,
+	"context.DeadlineExceeded": context.DeadlineExceeded,
+	"sql.ErrTxDone":            sql.ErrTxDone,
+	"sql.ErrConnDone":          sql.ErrConnDone,
+	"http.ErrAbortHandler":     http.ErrAbortHandler,
+	"io.EOF":                   io.EOF,
+	"io.ErrUnexpectedEOF":      io.ErrUnexpectedEOF,
+}
+
+const (
+	settingTLSCertificateFile = "tlscertificatefile"
+	settingTLSKeyFile         = "tlskeyfile"
+	settingTLSClientCAFile    = "tlsclientcafile"
+	settingTLSMinVersion      = "tlsminversion"
+)
+
+func isTLSSetting(name string) bool {
+	switch name {
+	case settingTLSCertificateFile, settingTLSKeyFile, settingTLSClientCAFile, settingTLSMinVersion:
+		return true
+	default:
+		return false
+	}
+}
+func loadTLSOptions(values map[string]string) (option, error) {
+	certificateFile, keyFile := values[settingTLSCertificateFile], values[settingTLSKeyFile]
+	if len(certificateFile) == 0 && len(keyFile) == 0 {
+		if len(values[settingTLSClientCAFile]) > 0 || len(values[settingTLSMinVersion]) > 0 {
+			return nil, errors.New("TLS settings require both [tls_certificate_file] and [tls_key_file]")
+		}
+		return nil, nil
+	}
+
+	config, err := loadTLSCertificate(certificateFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("[tls_certificate_file]/[tls_key_file]: %w", err)
+	}
+
+	if version := values[settingTLSMinVersion]; len(version) > 0 {
+		if config.MinVersion, err = parseTLSVersion(version); err != nil {
+			return nil, fmt.Errorf("[tls_min_version]: %w", err)
+		}
+	}
+
+	if path := values[settingTLSClientCAFile]; len(path) > 0 {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("[tls_client_ca_file]: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("[tls_client_ca_file]: no certificates found in [%s]", path)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return Options.TLSConfig(config), nil
+}
+func loadTLSCertificate(certificateFile, keyFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certificateFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}, nil
+}
+func parseTLSVersion(value string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version [%s]", value)
+	}
+}
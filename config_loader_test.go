@@ -0,0 +1,190 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestConfigLoaderFixture(t *testing.T) {
+	gunit.Run(new(ConfigLoaderFixture), t, gunit.Options.AllSequential()) // see setenv
+}
+
+type ConfigLoaderFixture struct {
+	*gunit.Fixture
+
+	directory string
+}
+
+func (this *ConfigLoaderFixture) Setup() {
+	this.directory, _ = os.MkdirTemp("", "httpserver-config-*")
+}
+func (this *ConfigLoaderFixture) Teardown() {
+	_ = os.RemoveAll(this.directory)
+}
+
+func (this *ConfigLoaderFixture) TestEnvironmentWithPrefix_MappedOntoConfiguration() {
+	this.setenv("TEST_LOADER_ENV_LISTEN_ADDRESS", "unix:///tmp/app.sock")
+	this.setenv("TEST_LOADER_ENV_READ_REQUEST_TIMEOUT", "7s")
+	this.setenv("TEST_LOADER_ENV_MAX_REQUEST_HEADER_SIZE", "4096")
+	this.setenv("TEST_LOADER_ENV_HANDLE_PANIC", "false")
+	this.setenv("TEST_LOADER_ENV_IGNORED_ERRORS", "io.EOF, context.Canceled")
+	this.setenv("TEST_LOADER_ENV_REQUEST_ID_HEADER", " X-Correlation-ID ")
+	this.setenv("TEST_LOADER_ENV_CONNECTION_LIMIT_PREFIX", "24, 64")
+
+	options, err := LoadEnvironment("TEST_LOADER_ENV_")
+	config := this.apply(options...)
+
+	this.So(err, should.BeNil)
+	this.So(config.ListenNetwork, should.Equal, "unix")
+	this.So(config.ListenAddress, should.Equal, "/tmp/app.sock")
+	this.So(config.ReadRequestTimeout, should.Equal, time.Second*7)
+	this.So(config.MaxRequestHeaderSize, should.Equal, 4096)
+	this.So(config.HandlePanic, should.BeFalse)
+	this.So(config.IgnoredErrors, should.Equal, []error{io.EOF, context.Canceled})
+	this.So(config.RequestIDHeader, should.Equal, "X-Correlation-ID")
+	this.So(config.ConnectionLimitIPv4Prefix, should.Equal, 24)
+	this.So(config.ConnectionLimitIPv6Prefix, should.Equal, 64)
+}
+func (this *ConfigLoaderFixture) TestEnvironmentWithInvalidValues_ErrorNamesEachSetting() {
+	this.setenv("TEST_LOADER_INVALID_SHUTDOWN_TIMEOUT", "soon")
+	this.setenv("TEST_LOADER_INVALID_DUMP_REQUEST_ON_PANIC", "maybe")
+	this.setenv("TEST_LOADER_INVALID_CONNECTION_LIMIT_PREFIX", "24")
+
+	_, err := LoadEnvironment("TEST_LOADER_INVALID_")
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "shutdowntimeout")
+	this.So(err.Error(), should.ContainSubstring, "dumprequestonpanic")
+	this.So(err.Error(), should.ContainSubstring, "connectionlimitprefix")
+}
+func (this *ConfigLoaderFixture) TestEnvironmentWithUnknownVariable_RejectedAsInFiles() {
+	this.setenv("TEST_LOADER_UNKNOWN_READ_REQUEST_TIMEOUT", "7s")
+	this.setenv("TEST_LOADER_UNKNOWN_PROXY", "http://proxy")
+
+	_, err := LoadEnvironment("TEST_LOADER_UNKNOWN_")
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "unknown setting [proxy]")
+}
+func (this *ConfigLoaderFixture) TestLoadedOptionsComposeWithExplicitOptions_LaterOptionsTakePrecedence() {
+	this.setenv("TEST_LOADER_COMPOSE_IDLE_CONNECTION_TIMEOUT", "1m")
+	this.setenv("TEST_LOADER_COMPOSE_WRITE_RESPONSE_TIMEOUT", "1m")
+
+	options, _ := LoadEnvironment("TEST_LOADER_COMPOSE_")
+	config := this.apply(append(options, Options.WriteResponseTimeout(time.Second))...)
+
+	this.So(config.IdleConnectionTimeout, should.Equal, time.Minute)
+	this.So(config.WriteResponseTimeout, should.Equal, time.Second)
+}
+func (this *ConfigLoaderFixture) TestJSONFile_MappedOntoConfiguration() {
+	path := this.write("settings.json", `{
+		"ListenAddress": "127.0.0.1:8080",
+		"write_response_timeout": "3s",
+		"max-request-header-size": 8192,
+		"dump_request_on_panic": true,
+		"ignored_errors": ["sql.ErrTxDone"]
+	}`)
+
+	options, err := LoadFile(path)
+	config := this.apply(options...)
+
+	this.So(err, should.BeNil)
+	this.So(config.ListenAddress, should.Equal, "127.0.0.1:8080")
+	this.So(config.WriteResponseTimeout, should.Equal, time.Second*3)
+	this.So(config.MaxRequestHeaderSize, should.Equal, 8192)
+	this.So(config.DumpRequestOnPanic, should.BeTrue)
+	this.So(len(config.IgnoredErrors), should.Equal, 1)
+}
+func (this *ConfigLoaderFixture) TestFlatFile_TOMLAndYAMLStyleLinesMappedOntoConfiguration() {
+	path := this.write("settings.conf", `
+# comment
+listen_address = "127.0.0.1:9090"
+shutdown_timeout: 9s
+force-shutdown-timeout = '2s'
+`)
+
+	options, err := LoadFile(path)
+	config := this.apply(options...)
+
+	this.So(err, should.BeNil)
+	this.So(config.ListenAddress, should.Equal, "127.0.0.1:9090")
+	this.So(config.ShutdownTimeout, should.Equal, time.Second*9)
+	this.So(config.ForceShutdownTimeout, should.Equal, time.Second*2)
+}
+func (this *ConfigLoaderFixture) TestFileWithUnknownKey_Rejected() {
+	path := this.write("settings.yaml", "listen_adress: 127.0.0.1:8080\n")
+
+	options, err := LoadFile(path)
+
+	this.So(options, should.BeEmpty)
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "listenadress")
+}
+func (this *ConfigLoaderFixture) TestMissingFile_Rejected() {
+	_, err := LoadFile(filepath.Join(this.directory, "missing.json"))
+
+	this.So(errors.Is(err, os.ErrNotExist), should.BeTrue)
+}
+func (this *ConfigLoaderFixture) TestTLSFiles_LoadedIntoTLSConfig() {
+	certificate, key := this.writeCertificate()
+	path := this.write("settings.toml", "tls_certificate_file = \""+certificate+"\"\ntls_key_file = \""+key+"\"\ntls_min_version = 1.3\n")
+
+	options, err := LoadFile(path)
+	config := this.apply(options...)
+
+	this.So(err, should.BeNil)
+	if this.So(config.TLSConfig, should.NotBeNil) {
+		this.So(config.TLSConfig.Certificates, should.HaveLength, 1)
+		this.So(config.TLSConfig.MinVersion, should.Equal, tls.VersionTLS13)
+	}
+}
+func (this *ConfigLoaderFixture) TestTLSCertificateWithoutKey_Rejected() {
+	certificate, _ := this.writeCertificate()
+	path := this.write("settings.toml", "tls_certificate_file = "+certificate+"\n")
+
+	_, err := LoadFile(path)
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+}
+
+func (this *ConfigLoaderFixture) apply(options ...option) (config configuration) {
+	Options.apply(options...)(&config)
+	return config
+}
+func (this *ConfigLoaderFixture) setenv(name, value string) {
+	this.T().(*testing.T).Setenv(name, value) // restored once the test completes, which mustn't run in parallel
+}
+func (this *ConfigLoaderFixture) write(name, contents string) string {
+	path := filepath.Join(this.directory, name)
+	_ = os.WriteFile(path, []byte(contents), 0600)
+	return path
+}
+func (this *ConfigLoaderFixture) writeCertificate() (certificate, key string) {
+	return writeTestCertificate(this.directory)
+}
+
+func writeTestCertificate(directory string) (certificate, key string) {
+	private, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	raw, _ := x509.CreateCertificate(rand.Reader, template, template, &private.PublicKey, private)
+	rawKey, _ := x509.MarshalECPrivateKey(private)
+	certificate, key = filepath.Join(directory, "cert.pem"), filepath.Join(directory, "key.pem")
+	_ = os.WriteFile(certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600)
+	_ = os.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600)
+	return certificate, key
+}
@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// connectionLifetime limits how long and for how many requests a single connection is reused. Once either limit is
+// reached the next response carries "Connection: close" which causes net/http to close an HTTP/1 connection after the
+// response and to send GOAWAY on an HTTP/2 connection. Connections reaching their maximum age while idle are closed.
+type connectionLifetime struct {
+	http.Handler
+	maxAge      time.Duration
+	maxJitter   time.Duration
+	maxRequests int
+
+	mutex       sync.Mutex
+	connections map[net.Conn]*trackedConnection
+}
+type trackedConnection struct {
+	net.Conn
+	expires  time.Time
+	requests int
+	state    http.ConnState
+	timer    *time.Timer
+}
+
+func newConnectionLifetime(handler http.Handler, maxAge, maxJitter time.Duration, maxRequests int) *connectionLifetime {
+	return &connectionLifetime{
+		Handler:     handler,
+		maxAge:      maxAge,
+		maxJitter:   maxJitter,
+		maxRequests: maxRequests,
+		connections: make(map[net.Conn]*trackedConnection),
+	}
+}
+
+func (this *connectionLifetime) ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	tracked := &trackedConnection{Conn: conn, state: http.StateNew}
+	if this.maxAge > 0 {
+		age := this.maxAge
+		if this.maxJitter > 0 {
+			age += rand.N(this.maxJitter) // spreads reconnects such that clients don't all reconnect at once
+		}
+		tracked.expires = time.Now().Add(age)
+		tracked.timer = time.AfterFunc(age, func() { this.expire(tracked) })
+	}
+
+	this.mutex.Lock()
+	this.connections[conn] = tracked
+	this.mutex.Unlock()
+
+	return context.WithValue(ctx, trackedConnectionKey{}, tracked)
+}
+func (this *connectionLifetime) ConnState(conn net.Conn, state http.ConnState) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	tracked, found := this.connections[conn]
+	if !found {
+		return
+	}
+
+	tracked.state = state
+	switch state {
+	case http.StateIdle:
+		if this.expired(tracked) {
+			_ = conn.Close()
+		}
+	case http.StateHijacked, http.StateClosed:
+		if tracked.timer != nil {
+			tracked.timer.Stop()
+		}
+		delete(this.connections, conn)
+	}
+}
+func (this *connectionLifetime) expire(tracked *trackedConnection) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if tracked.state == http.StateIdle || tracked.state == http.StateNew {
+		_ = tracked.Close() // otherwise closed once the in-flight response has been written
+	}
+}
+func (this *connectionLifetime) expired(tracked *trackedConnection) bool {
+	return (this.maxRequests > 0 && tracked.requests >= this.maxRequests) ||
+		(this.maxAge > 0 && !time.Now().Before(tracked.expires))
+}
+
+func (this *connectionLifetime) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if tracked, ok := request.Context().Value(trackedConnectionKey{}).(*trackedConnection); ok {
+		this.mutex.Lock()
+		tracked.requests++
+		expired := this.expired(tracked)
+		this.mutex.Unlock()
+
+		if expired {
+			response.Header().Set("Connection", "close")
+		}
+	}
+
+	this.Handler.ServeHTTP(response, request)
+}
+
+type trackedConnectionKey struct{}
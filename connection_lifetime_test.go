@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestConnectionLifetimeFixture(t *testing.T) {
+	gunit.Run(new(ConnectionLifetimeFixture), t)
+}
+
+type ConnectionLifetimeFixture struct {
+	*gunit.Fixture
+
+	server *httptest.Server
+	client *http.Client
+}
+
+func (this *ConnectionLifetimeFixture) Teardown() {
+	if this.server != nil {
+		this.client.CloseIdleConnections()
+		this.server.Close()
+	}
+}
+func (this *ConnectionLifetimeFixture) initialize(options ...option) {
+	var config configuration
+	Options.apply(options...)(&config)
+	this.server = httptest.NewUnstartedServer(nil)
+	this.server.Config = config.HTTPServer.(*http.Server)
+	this.server.Start()
+	this.client = this.server.Client()
+}
+
+func (this *ConnectionLifetimeFixture) TestNoLimits_NotTracked() {
+	this.initialize()
+
+	this.So(this.server.Config.ConnContext, should.BeNil)
+	this.So(this.get().Close, should.BeFalse)
+}
+func (this *ConnectionLifetimeFixture) TestMaxRequestsReached_ConnectionClosedAfterResponse() {
+	this.initialize(Options.MaxConnectionRequests(2))
+
+	first, firstReused := this.getReused()
+	second, secondReused := this.getReused()
+	third, thirdReused := this.getReused()
+
+	this.So(first.Close, should.BeFalse)
+	this.So(firstReused, should.BeFalse)
+	this.So(second.Close, should.BeTrue)
+	this.So(secondReused, should.BeTrue)
+	this.So(third.Close, should.BeFalse)
+	this.So(thirdReused, should.BeFalse)
+}
+func (this *ConnectionLifetimeFixture) TestMaxAgeReachedWhileIdle_ConnectionClosed() {
+	this.initialize(Options.MaxConnectionAge(time.Millisecond * 20))
+
+	first, _ := this.getReused()
+	time.Sleep(time.Millisecond * 30)
+	_, reused := this.getReused()
+
+	this.So(first.Close, should.BeFalse)
+	this.So(reused, should.BeFalse)
+}
+func (this *ConnectionLifetimeFixture) TestMaxAgeReachedDuringRequest_ConnectionClosedOnceResponseWritten() {
+	this.initialize(
+		Options.MaxConnectionAge(time.Millisecond*20),
+		Options.Handler(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			time.Sleep(time.Millisecond * 30)
+			_, _ = io.WriteString(response, "complete")
+		})),
+	)
+
+	first, _ := this.getReused()
+	_, reused := this.getReused()
+
+	this.So(first.StatusCode, should.Equal, 200)
+	this.So(reused, should.BeFalse)
+}
+func (this *ConnectionLifetimeFixture) TestMaxAgeWithJitter_ExpiryWithinJitterRange() {
+	lifetime := newConnectionLifetime(nil, time.Minute, time.Second, 0)
+	server, client := net.Pipe()
+	defer func() { _ = server.Close(); _ = client.Close() }()
+
+	ctx := lifetime.ConnContext(this.T().Context(), server)
+	tracked := ctx.Value(trackedConnectionKey{}).(*trackedConnection)
+	defer tracked.timer.Stop()
+
+	this.So(tracked.expires, should.HappenBetween, time.Now().Add(time.Minute-time.Second), time.Now().Add(time.Minute+time.Second))
+	lifetime.ConnState(server, http.StateClosed)
+	this.So(lifetime.connections, should.BeEmpty)
+}
+
+func (this *ConnectionLifetimeFixture) get() *http.Response {
+	response, _ := this.getReused()
+	return response
+}
+func (this *ConnectionLifetimeFixture) getReused() (response *http.Response, reused bool) {
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	request, _ := http.NewRequestWithContext(httptrace.WithClientTrace(this.T().Context(), trace), "GET", this.server.URL, nil)
+	response, err := this.client.Do(request)
+	if err != nil {
+		this.Error(err)
+		return &http.Response{}, false
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+	return response, reused
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 )
 
 type ListenCloser interface {
@@ -12,6 +13,26 @@ type ListenCloser interface {
 	io.Closer
 }
 
+// HandlerSwapper is implemented by the value returned from New. Swapping replaces the root handler for all subsequent
+// requests, while requests already in flight complete using the handler they started with. Any panic recovery
+// configured through Options continues to wrap the new handler. The new handler generation is returned.
+type HandlerSwapper interface {
+	SwapHandler(http.Handler) uint64
+}
+
+// Reloader is implemented by the value returned from New. Reloading applies a subset of the settings configured through
+// Options to the running server without unbinding the listener. An error describing every rejected setting is returned
+// and nothing is applied when any of the settings provided cannot be reloaded or are invalid.
+type Reloader interface {
+	Reload(options ...option) error
+}
+
+// SpanExporter receives each sampled server span once the response has been written (see Options.TraceExporter). An
+// exporter which also implements Flush(context.Context) error is flushed once the server has shut down.
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
 type logger interface {
 	Printf(string, ...any)
 }
@@ -19,6 +40,80 @@ type monitor interface {
 	PanicRecovered(request *http.Request, err any)
 }
 
+// ignoredPanicMonitor may optionally be implemented by the monitor provided to receive each recovered panic which was
+// not reported through PanicRecovered because it matched one of the IgnoredErrors.
+type ignoredPanicMonitor interface {
+	PanicIgnored(request *http.Request, err any)
+}
+
+// abortedPanicMonitor may optionally be implemented by the monitor provided to receive (instead of through
+// PanicRecovered) each recovered panic which occurred after the response status was sent, such that the response was
+// aborted rather than replaced by an error response.
+type abortedPanicMonitor interface {
+	PanicAborted(request *http.Request, err any)
+}
+
+// panicReportMonitor may optionally be implemented by the monitor provided to receive a structured report of each
+// recovered panic (other than those matching the IgnoredErrors), e.g. for delivery to an error tracker.
+type panicReportMonitor interface {
+	PanicReported(report PanicReport)
+}
+
+// connectionLimitMonitor may optionally be implemented by the monitor provided to receive each connection rejected due
+// to either MaxConnections or MaxConnectionsPerClient (perClient).
+type connectionLimitMonitor interface {
+	ConnectionLimitReached(remoteAddress net.Addr, perClient bool)
+}
+
+// slowClientMonitor may optionally be implemented by the monitor provided to receive each request aborted due to
+// either MinRequestBodyRate or MinResponseRate (writing).
+type slowClientMonitor interface {
+	SlowClientAborted(request *http.Request, writing bool, bytesPerSecond float64)
+}
+
+// requestMonitor may optionally be implemented by the monitor provided to receive each request as it starts and once
+// it finishes along with the status (zero when hijacked or the handler panicked without recovery), the number of body
+// bytes written and the time taken.
+type requestMonitor interface {
+	RequestStarted(request *http.Request)
+	RequestFinished(request *http.Request, status int, bytesWritten int64, duration time.Duration)
+}
+
+// connectionStateMonitor may optionally be implemented by the monitor provided to receive each connection state
+// transition reported by the http.Server. The same net.Conn is provided for every transition of a connection, ending
+// with http.StateClosed or http.StateHijacked, such that it may be used to key per-connection state.
+type connectionStateMonitor interface {
+	ConnectionStateChanged(conn net.Conn, state http.ConnState)
+}
+
+// tlsHandshakeMonitor may optionally be implemented by the monitor provided to receive each failed TLS handshake.
+type tlsHandshakeMonitor interface {
+	TLSHandshakeFailed(remoteAddress string, reason string)
+}
+
+// serverLogMonitor may optionally be implemented by the monitor provided to count each message written by the
+// http.Server to its ErrorLog by class, including those suppressed (see Options.SuppressServerLog and
+// Options.ServerLogRateLimit).
+type serverLogMonitor interface {
+	ServerLogged(class ServerLogClass, suppressed bool)
+}
+
+// listenMonitor may optionally be implemented by the monitor provided to learn whether the listener was bound.
+type listenMonitor interface {
+	ListenSucceeded(network, address string)
+	ListenFailed(network, address string, err error)
+}
+
+// shutdownMonitor may optionally be implemented by the monitor provided to receive each phase of shutdown along with
+// the time elapsed since shutdown started.
+type shutdownMonitor interface {
+	ShutdownPhaseEntered(phase ShutdownPhase, elapsed time.Duration)
+}
+
+type spanFlusher interface {
+	Flush(ctx context.Context) error
+}
+
 type httpServer interface {
 	Serve(listener net.Listener) error
 	Shutdown(ctx context.Context) error
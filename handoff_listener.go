@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"net"
+	"sync"
+)
+
+// handoffListener allows a single bound listener to be served by successive http.Server instances. Each server is
+// given its own view which may be closed (e.g. by http.Server.Shutdown) without closing the underlying listener, such
+// that a replacement server can continue accepting connections from the same socket.
+type handoffListener struct {
+	net.Listener
+	accepted chan acceptResult
+	closed   chan struct{}
+	once     sync.Once
+}
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newHandoffListener(inner net.Listener) *handoffListener {
+	this := &handoffListener{Listener: inner, accepted: make(chan acceptResult), closed: make(chan struct{})}
+	go this.acceptLoop()
+	return this
+}
+
+func (this *handoffListener) acceptLoop() {
+	for {
+		conn, err := this.Listener.Accept()
+		select {
+		case this.accepted <- acceptResult{conn: conn, err: err}:
+		case <-this.closed:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (this *handoffListener) View() net.Listener {
+	return &handoffView{handoffListener: this, closed: make(chan struct{})}
+}
+
+func (this *handoffListener) Close() (err error) {
+	this.once.Do(func() {
+		close(this.closed)
+		err = this.Listener.Close()
+	})
+	return err
+}
+
+type handoffView struct {
+	*handoffListener
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (this *handoffView) Accept() (net.Conn, error) {
+	select {
+	case result := <-this.accepted:
+		return result.conn, result.err
+	case <-this.closed:
+		return nil, net.ErrClosed
+	case <-this.handoffListener.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (this *handoffView) Close() error {
+	this.once.Do(func() { close(this.closed) })
+	return nil
+}
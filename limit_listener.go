@@ -0,0 +1,217 @@
+package httpserver
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// ConnectionLimitBehavior determines what happens to a connection which would exceed the configured limits.
+type ConnectionLimitBehavior uint8
+
+const (
+	// ConnectionLimitBlock stops accepting connections while the total limit is reached, such that pending connections
+	// wait in the listen backlog. Connections exceeding the per-client limit are closed.
+	ConnectionLimitBlock ConnectionLimitBehavior = iota
+
+	// ConnectionLimitClose accepts and immediately closes the connection.
+	ConnectionLimitClose
+
+	// ConnectionLimitServiceUnavailable accepts the connection, writes an HTTP 503 response, and closes it. Connections
+	// on a TLS listener are closed without a response as the handshake has not yet taken place.
+	ConnectionLimitServiceUnavailable
+)
+
+func (this ConnectionLimitBehavior) String() string {
+	switch this {
+	case ConnectionLimitBlock:
+		return "block"
+	case ConnectionLimitClose:
+		return "close"
+	case ConnectionLimitServiceUnavailable:
+		return "503"
+	default:
+		return "unknown"
+	}
+}
+
+type limitListener struct {
+	net.Listener
+	slots        chan struct{}
+	closed       chan struct{}
+	closeOnce    sync.Once
+	maxPerClient int
+	ipv4Prefix   int
+	ipv6Prefix   int
+	behavior     ConnectionLimitBehavior
+	plaintext    bool
+	monitor      connectionLimitMonitor
+
+	mutex   sync.Mutex
+	clients map[netip.Prefix]int
+}
+
+func newLimitListener(inner net.Listener, config configuration) net.Listener {
+	if config.MaxConnections <= 0 && config.MaxConnectionsPerClient <= 0 {
+		return inner
+	}
+
+	this := &limitListener{
+		Listener:     inner,
+		closed:       make(chan struct{}),
+		maxPerClient: config.MaxConnectionsPerClient,
+		ipv4Prefix:   config.ConnectionLimitIPv4Prefix,
+		ipv6Prefix:   config.ConnectionLimitIPv6Prefix,
+		behavior:     config.ConnectionLimitBehavior,
+		plaintext:    config.TLSConfig == nil,
+		clients:      make(map[netip.Prefix]int),
+	}
+	this.monitor, _ = config.Monitor.(connectionLimitMonitor)
+	if config.MaxConnections > 0 {
+		this.slots = make(chan struct{}, config.MaxConnections)
+	}
+	return this
+}
+
+func (this *limitListener) Accept() (net.Conn, error) {
+	for {
+		if this.behavior == ConnectionLimitBlock && this.slots != nil {
+			select {
+			case this.slots <- struct{}{}:
+			case <-this.closed:
+				return nil, net.ErrClosed
+			}
+		}
+
+		conn, err := this.Listener.Accept()
+		if err != nil {
+			if this.behavior == ConnectionLimitBlock && this.slots != nil {
+				<-this.slots
+			}
+			return nil, err
+		}
+
+		if admitted := this.admit(conn); admitted != nil {
+			return admitted, nil
+		}
+	}
+}
+func (this *limitListener) admit(conn net.Conn) net.Conn {
+	blocked := this.behavior == ConnectionLimitBlock && this.slots != nil // slot was acquired prior to accepting
+
+	if !blocked && this.slots != nil {
+		select {
+		case this.slots <- struct{}{}:
+		default:
+			this.reject(conn, false)
+			return nil
+		}
+	}
+
+	client, perClient := this.clientPrefix(conn.RemoteAddr())
+	if perClient && !this.acquireClient(client) {
+		if this.slots != nil {
+			<-this.slots
+		}
+		this.reject(conn, true)
+		return nil
+	}
+
+	return &limitConn{Conn: conn, release: func() {
+		if perClient {
+			this.releaseClient(client)
+		}
+		if this.slots != nil {
+			<-this.slots
+		}
+	}}
+}
+func (this *limitListener) clientPrefix(address net.Addr) (netip.Prefix, bool) {
+	if this.maxPerClient <= 0 {
+		return netip.Prefix{}, false
+	}
+
+	parsed, err := netip.ParseAddrPort(address.String())
+	if err != nil {
+		return netip.Prefix{}, false // e.g. UNIX domain sockets
+	}
+
+	ip := parsed.Addr().Unmap()
+	bits := this.ipv6Prefix
+	if ip.Is4() {
+		bits = this.ipv4Prefix
+	}
+
+	prefix, err := ip.Prefix(bits)
+	return prefix, err == nil
+}
+func (this *limitListener) acquireClient(client netip.Prefix) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.clients[client] >= this.maxPerClient {
+		return false
+	}
+
+	this.clients[client]++
+	return true
+}
+func (this *limitListener) releaseClient(client netip.Prefix) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.clients[client]--; this.clients[client] <= 0 {
+		delete(this.clients, client)
+	}
+}
+func (this *limitListener) reject(conn net.Conn, perClient bool) {
+	if this.monitor != nil {
+		this.monitor.ConnectionLimitReached(conn.RemoteAddr(), perClient)
+	}
+
+	if this.behavior == ConnectionLimitServiceUnavailable && this.plaintext {
+		go writeServiceUnavailable(conn)
+	} else {
+		_ = conn.Close()
+	}
+}
+
+func (this *limitListener) Close() error {
+	this.closeOnce.Do(func() { close(this.closed) })
+	return this.Listener.Close()
+}
+
+func writeServiceUnavailable(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = io.WriteString(conn, serviceUnavailableResponse)
+
+	// closing a socket with unread request data causes a reset which may discard the response before the client reads it
+	if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = closer.CloseWrite()
+		_, _ = io.Copy(io.Discard, io.LimitReader(conn, 1024*64))
+	}
+}
+
+const serviceUnavailableResponse = "HTTP/1.1 503 Service Unavailable\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Length: 20\r\n" +
+	"Retry-After: 1\r\n" +
+	"Connection: close\r\n" +
+	"\r\n" +
+	"Service Unavailable\n"
+
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (this *limitConn) Close() error {
+	err := this.Conn.Close()
+	this.once.Do(this.release)
+	return err
+}
@@ -0,0 +1,158 @@
+package httpserver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestLimitListenerFixture(t *testing.T) {
+	gunit.Run(new(LimitListenerFixture), t)
+}
+
+type LimitListenerFixture struct {
+	*gunit.Fixture
+
+	inner    net.Listener
+	listener net.Listener
+	accepted chan net.Conn
+	clients  []net.Conn
+
+	mutex     sync.Mutex
+	rejected  []net.Addr
+	perClient []bool
+}
+
+func (this *LimitListenerFixture) Setup() {
+	this.inner, _ = net.Listen("tcp", "127.0.0.1:0")
+	this.accepted = make(chan net.Conn, 16)
+}
+func (this *LimitListenerFixture) Teardown() {
+	_ = this.listener.Close()
+	for _, client := range this.clients {
+		_ = client.Close()
+	}
+}
+func (this *LimitListenerFixture) initialize(options ...option) {
+	var config configuration
+	Options.apply(append(options, Options.Monitor(this))...)(&config)
+	this.listener = newLimitListener(this.inner, config)
+	go func() {
+		for {
+			conn, err := this.listener.Accept()
+			if err != nil {
+				return
+			}
+			this.accepted <- conn
+		}
+	}()
+}
+
+func (this *LimitListenerFixture) TestNoLimits_ListenerNotWrapped() {
+	this.initialize()
+
+	this.So(this.listener, should.Equal, this.inner)
+}
+func (this *LimitListenerFixture) TestTotalLimitReached_CloseBehavior_ExcessConnectionClosedAndReported() {
+	this.initialize(Options.MaxConnections(1), Options.ConnectionLimitBehavior(ConnectionLimitClose))
+
+	this.dial("127.0.0.1")
+	admitted := <-this.accepted
+	second := this.dial("127.0.0.1")
+
+	this.So(this.read(second), should.BeEmpty)
+	this.So(this.rejectedCount(), should.Equal, 1)
+	this.So(this.perClient[0], should.BeFalse)
+
+	_ = admitted.Close()
+	this.dial("127.0.0.1")
+	this.So(this.nextAccepted(), should.NotBeNil)
+}
+func (this *LimitListenerFixture) TestTotalLimitReached_ServiceUnavailableBehavior_ExcessConnectionReceives503() {
+	this.initialize(Options.MaxConnections(1), Options.ConnectionLimitBehavior(ConnectionLimitServiceUnavailable))
+
+	this.dial("127.0.0.1")
+	<-this.accepted
+	second := this.dial("127.0.0.1")
+
+	this.So(this.read(second), should.StartWith, "HTTP/1.1 503 Service Unavailable\r\n")
+	this.So(this.rejectedCount(), should.Equal, 1)
+}
+func (this *LimitListenerFixture) TestTotalLimitReached_BlockBehavior_AcceptWaitsForSlot() {
+	this.initialize(Options.MaxConnections(1), Options.ConnectionLimitBehavior(ConnectionLimitBlock))
+
+	this.dial("127.0.0.1")
+	admitted := <-this.accepted
+	this.dial("127.0.0.1")
+
+	this.So(this.nextAccepted(), should.BeNil)
+	_ = admitted.Close()
+	this.So(this.nextAccepted(), should.NotBeNil)
+	this.So(this.rejectedCount(), should.Equal, 0)
+}
+func (this *LimitListenerFixture) TestPerClientLimitReached_OtherClientsStillAdmitted() {
+	this.initialize(Options.MaxConnectionsPerClient(1), Options.ConnectionLimitBehavior(ConnectionLimitBlock))
+
+	this.dial("127.0.0.1")
+	<-this.accepted
+	second := this.dial("127.0.0.1")
+	this.So(this.read(second), should.BeEmpty)
+	this.dial("127.0.0.2")
+
+	this.So(this.nextAccepted(), should.NotBeNil)
+	this.So(this.rejectedCount(), should.Equal, 1)
+	this.So(this.perClient[0], should.BeTrue)
+}
+func (this *LimitListenerFixture) TestPerClientLimitWithPrefix_ClientsInSameNetworkShareLimit() {
+	this.initialize(Options.MaxConnectionsPerClient(1), Options.ConnectionLimitPrefix(24, 64))
+
+	this.dial("127.0.0.1")
+	<-this.accepted
+	second := this.dial("127.0.0.2")
+
+	this.So(this.read(second), should.BeEmpty)
+	this.So(this.rejectedCount(), should.Equal, 1)
+}
+
+func (this *LimitListenerFixture) dial(local string) net.Conn {
+	dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(local)}}
+	conn, err := dialer.Dial("tcp", this.inner.Addr().String())
+	if err != nil {
+		this.Error(err)
+		return nil
+	}
+	this.clients = append(this.clients, conn)
+	return conn
+}
+func (this *LimitListenerFixture) read(conn net.Conn) string {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond * 500))
+	raw, _ := io.ReadAll(conn)
+	return string(raw)
+}
+func (this *LimitListenerFixture) nextAccepted() net.Conn {
+	select {
+	case conn := <-this.accepted:
+		return conn
+	case <-time.After(time.Millisecond * 50):
+		return nil
+	}
+}
+func (this *LimitListenerFixture) rejectedCount() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.rejected)
+}
+
+func (this *LimitListenerFixture) PanicRecovered(*http.Request, any) {}
+func (this *LimitListenerFixture) ConnectionLimitReached(remoteAddress net.Addr, perClient bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.rejected = append(this.rejected, remoteAddress)
+	this.perClient = append(this.perClient, perClient)
+}
@@ -0,0 +1,207 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type socketListenConfig struct {
+	net.ListenConfig
+	socketOptions
+	backlog    int
+	socketMode os.FileMode
+	noDelay    bool
+}
+
+func newSocketListenConfig(config configuration) *socketListenConfig {
+	this := &socketListenConfig{
+		socketOptions: socketOptions{
+			reusePort:     config.ListenReusePort,
+			fastOpen:      config.ListenFastOpen,
+			deferAccept:   config.ListenDeferAccept,
+			receiveBuffer: config.ListenReceiveBuffer,
+			sendBuffer:    config.ListenSendBuffer,
+			userTimeout:   config.ListenUserTimeout,
+			ipv6Only:      config.ListenIPv6Only,
+		},
+		backlog:    config.ListenBacklog,
+		socketMode: config.ListenSocketMode,
+		noDelay:    config.ListenNoDelay,
+	}
+
+	this.Control = this.control
+	if config.ListenKeepAlive < 0 {
+		this.KeepAlive = -1
+	} else {
+		this.KeepAliveConfig = net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     config.ListenKeepAlive,
+			Interval: config.ListenKeepAliveInterval,
+			Count:    config.ListenKeepAliveCount,
+		}
+	}
+
+	return this
+}
+
+func (this *socketListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	listener, err := this.ListenConfig.Listen(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = this.configure(listener, network, address); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	if !this.noDelay {
+		listener = &delayListener{Listener: listener}
+	}
+
+	return listener, nil
+}
+func (this *socketListenConfig) configure(listener net.Listener, network, address string) error {
+	if this.backlog > 0 {
+		if err := this.listenBacklog(listener); err != nil {
+			return fmt.Errorf("unable to set listen backlog: %w", err)
+		}
+	}
+
+	if this.socketMode != 0 && strings.HasPrefix(network, "unix") && !strings.HasPrefix(address, "@") {
+		if err := os.Chmod(address, this.socketMode); err != nil {
+			return fmt.Errorf("unable to set socket mode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type socketOptions struct {
+	reusePort     bool
+	fastOpen      int
+	deferAccept   time.Duration
+	receiveBuffer int
+	sendBuffer    int
+	userTimeout   time.Duration
+	ipv6Only      bool
+}
+
+// delayListener re-enables Nagle's algorithm on accepted connections, which Go otherwise disables on every TCP
+// connection regardless of the setting on the listening socket.
+type delayListener struct{ net.Listener }
+
+func (this *delayListener) Accept() (net.Conn, error) {
+	conn, err := this.Listener.Accept()
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetNoDelay(false)
+	}
+	return conn, err
+}
+
+// applyListenQuery applies the listener settings found in the query string of the listen address:
+//   - tls=<certificate file>,<key file>: serve TLS using the certificate and key provided
+//   - backlog=<int>: see Options.ListenBacklog
+//   - keepalive=<duration>: see Options.ListenKeepAlive
+//   - keepaliveinterval=<duration>: see Options.ListenKeepAliveInterval
+//   - keepalivecount=<int>: see Options.ListenKeepAliveCount
+//   - nodelay=<bool>: see Options.ListenNoDelay
+//   - fastopen=<int>: see Options.ListenFastOpen
+//   - deferaccept=<duration>: see Options.ListenDeferAccept
+//   - rcvbuf=<int>: see Options.ListenReceiveBuffer
+//   - sndbuf=<int>: see Options.ListenSendBuffer
+//   - usertimeout=<duration>: see Options.ListenUserTimeout
+//   - v6only=<bool>: see Options.ListenIPv6Only
+//   - reuseport=<bool>: see Options.ListenReusePort
+//   - mode=<octal>: see Options.ListenSocketMode (UNIX domain sockets only)
+//
+// Each parameter may be specified at most once.
+func (this *configuration) applyListenQuery(query url.Values, certificate func() (*tls.Config, error)) error {
+	var errs []error
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(query[name]) > 1 {
+			errs = append(errs, fmt.Errorf("%w: ListenAddress parameter [%s]: specified %d times", ErrInvalidSetting, name, len(query[name])))
+		} else if err := this.applyListenQueryValue(name, query.Get(name), certificate); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ListenAddress parameter [%s]: %w", ErrInvalidSetting, name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+func (this *configuration) applyListenQueryValue(name, value string, certificate func() (*tls.Config, error)) (err error) {
+	switch name {
+	case "tls":
+		this.TLSConfig, err = certificate()
+	case "backlog":
+		this.ListenBacklog, err = parsePositiveInt(value)
+	case "keepalive":
+		this.ListenKeepAlive, err = time.ParseDuration(value)
+	case "keepaliveinterval":
+		this.ListenKeepAliveInterval, err = parsePositiveDuration(value)
+	case "keepalivecount":
+		this.ListenKeepAliveCount, err = parsePositiveInt(value)
+	case "nodelay":
+		this.ListenNoDelay, err = strconv.ParseBool(value)
+	case "fastopen":
+		this.ListenFastOpen, err = parsePositiveInt(value)
+	case "deferaccept":
+		this.ListenDeferAccept, err = parsePositiveDuration(value)
+	case "rcvbuf":
+		this.ListenReceiveBuffer, err = parsePositiveInt(value)
+	case "sndbuf":
+		this.ListenSendBuffer, err = parsePositiveInt(value)
+	case "usertimeout":
+		this.ListenUserTimeout, err = parsePositiveDuration(value)
+	case "v6only":
+		this.ListenIPv6Only, err = strconv.ParseBool(value)
+	case "reuseport":
+		this.ListenReusePort, err = strconv.ParseBool(value)
+	case "mode":
+		var mode uint64
+		if this.ListenNetwork != "unix" {
+			err = errors.New("only supported for UNIX domain sockets")
+		} else if mode, err = strconv.ParseUint(value, 8, 32); err == nil {
+			this.ListenSocketMode = os.FileMode(mode) & os.ModePerm
+		}
+	default:
+		err = errors.New("unknown parameter")
+	}
+	return err
+}
+func loadListenCertificate(value string) (*tls.Config, error) {
+	certificateFile, keyFile, found := strings.Cut(value, ",")
+	if !found {
+		return nil, errors.New("expected [<certificate file>,<key file>]")
+	}
+	return loadTLSCertificate(certificateFile, keyFile)
+}
+func parsePositiveInt(value string) (int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err == nil && parsed <= 0 {
+		err = errors.New("must be positive")
+	}
+	return parsed, err
+}
+func parsePositiveDuration(value string) (time.Duration, error) {
+	parsed, err := time.ParseDuration(value)
+	if err == nil && parsed <= 0 {
+		err = errors.New("must be positive")
+	}
+	return parsed, err
+}
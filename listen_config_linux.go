@@ -0,0 +1,27 @@
+package httpserver
+
+import (
+	"syscall"
+	"time"
+)
+
+// The following TCP-level socket options are not exposed by the syscall package. They're set on the listening socket
+// and inherited by each accepted connection.
+const (
+	socketTCPDeferAccept = 9  // TCP_DEFER_ACCEPT: seconds to wait for data before completing accept
+	socketTCPUserTimeout = 18 // TCP_USER_TIMEOUT: milliseconds that transmitted data may remain unacknowledged
+	socketTCPFastOpen    = 23 // TCP_FASTOPEN: length of the queue of pending fast-open requests
+)
+
+func (this socketOptions) controlTCP(set func(name string, level, option, value int)) []error {
+	if this.fastOpen > 0 {
+		set("TCP_FASTOPEN", syscall.IPPROTO_TCP, socketTCPFastOpen, this.fastOpen)
+	}
+	if this.deferAccept > 0 {
+		set("TCP_DEFER_ACCEPT", syscall.IPPROTO_TCP, socketTCPDeferAccept, int((this.deferAccept+time.Second-1)/time.Second))
+	}
+	if this.userTimeout > 0 {
+		set("TCP_USER_TIMEOUT", syscall.IPPROTO_TCP, socketTCPUserTimeout, int(this.userTimeout/time.Millisecond))
+	}
+	return nil
+}
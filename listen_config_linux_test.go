@@ -0,0 +1,44 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/smarty/gunit/assert/should"
+)
+
+func (this *ListenConfigFixture) TestBindTCPSocket_SocketOptionsApplied() {
+	config := this.apply(
+		Options.ListenAddress("127.0.0.1:0"),
+		Options.ListenReceiveBuffer(65536),
+		Options.ListenSendBuffer(65536),
+		Options.ListenDeferAccept(time.Millisecond*1500),
+		Options.ListenUserTimeout(time.Second*30),
+		Options.ListenReusePort(false),
+		Options.ListenNoDelay(false),
+		Options.ListenBacklog(64),
+	)
+
+	listener, err := config.ListenConfig.Listen(context.Background(), config.ListenNetwork, config.ListenAddress)
+	if !this.So(err, should.BeNil) {
+		return
+	}
+	defer func() { _ = listener.Close() }()
+
+	this.So(listener, should.HaveSameTypeAs, &delayListener{})
+	this.So(this.getsockopt(listener, syscall.SOL_SOCKET, socketReusePort), should.Equal, 0)
+	this.So(this.getsockopt(listener, syscall.SOL_SOCKET, syscall.SO_RCVBUF), should.BeGreaterThanOrEqualTo, 65536)
+	this.So(this.getsockopt(listener, syscall.SOL_SOCKET, syscall.SO_SNDBUF), should.BeGreaterThanOrEqualTo, 65536)
+	this.So(this.getsockopt(listener, syscall.IPPROTO_TCP, socketTCPDeferAccept), should.BeGreaterThan, 0)
+	this.So(this.getsockopt(listener, syscall.IPPROTO_TCP, socketTCPUserTimeout), should.Equal, 30000)
+}
+func (this *ListenConfigFixture) getsockopt(listener net.Listener, level, option int) (value int) {
+	if delayed, ok := listener.(*delayListener); ok {
+		listener = delayed.Listener
+	}
+	raw, _ := listener.(syscall.Conn).SyscallConn()
+	_ = raw.Control(func(descriptor uintptr) { value, _ = syscall.GetsockoptInt(int(descriptor), level, option) })
+	return value
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// controlTCP rejects the TCP options whose socket option numbers are specific to Linux.
+func (this socketOptions) controlTCP(func(name string, level, option, value int)) (errs []error) {
+	for _, item := range []struct {
+		name       string
+		configured bool
+	}{
+		{name: "TCP_FASTOPEN", configured: this.fastOpen > 0},
+		{name: "TCP_DEFER_ACCEPT", configured: this.deferAccept > 0},
+		{name: "TCP_USER_TIMEOUT", configured: this.userTimeout > 0},
+	} {
+		if item.configured {
+			errs = append(errs, fmt.Errorf("unable to set %s: %w", item.name, errUnsupportedSocketOption))
+		}
+	}
+	return errs
+}
+
+var errUnsupportedSocketOption = errors.New("not supported on this platform")
@@ -0,0 +1,132 @@
+package httpserver
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestListenConfigFixture(t *testing.T) {
+	gunit.Run(new(ListenConfigFixture), t)
+}
+
+type ListenConfigFixture struct {
+	*gunit.Fixture
+
+	directory string
+}
+
+func (this *ListenConfigFixture) Setup() {
+	this.directory, _ = os.MkdirTemp("", "httpserver-listen-*")
+}
+func (this *ListenConfigFixture) Teardown() {
+	_ = os.RemoveAll(this.directory)
+}
+
+func (this *ListenConfigFixture) TestTCPAddressWithQuery_ListenerSettingsApplied() {
+	config := this.apply(Options.ListenAddress("tcp://0.0.0.0:8443?backlog=4096&keepalive=30s&reuseport=false"))
+
+	this.So(config.ListenAddressError, should.BeNil)
+	this.So(config.ListenNetwork, should.Equal, "tcp")
+	this.So(config.ListenAddress, should.Equal, "0.0.0.0:8443")
+	this.So(config.ListenBacklog, should.Equal, 4096)
+	this.So(config.ListenKeepAlive, should.Equal, time.Second*30)
+	this.So(config.ListenReusePort, should.BeFalse)
+	this.So(config.ListenConfig.(*socketListenConfig).backlog, should.Equal, 4096)
+	this.So(config.ListenConfig.(*socketListenConfig).KeepAliveConfig.Idle, should.Equal, time.Second*30)
+}
+func (this *ListenConfigFixture) TestHostPortWithQuery_ListenerSettingsApplied() {
+	config := this.apply(Options.ListenAddress("127.0.0.1:8080?backlog=16"))
+
+	this.So(config.ListenAddressError, should.BeNil)
+	this.So(config.ListenNetwork, should.Equal, "tcp")
+	this.So(config.ListenAddress, should.Equal, "127.0.0.1:8080")
+	this.So(config.ListenBacklog, should.Equal, 16)
+	this.So(config.ListenReusePort, should.BeTrue)
+}
+func (this *ListenConfigFixture) TestUnixAddressWithMode_ListenerSettingsApplied() {
+	config := this.apply(Options.ListenAddress("unix:///run/app.sock?mode=0660"))
+
+	this.So(config.ListenAddressError, should.BeNil)
+	this.So(config.ListenNetwork, should.Equal, "unix")
+	this.So(config.ListenAddress, should.Equal, "/run/app.sock")
+	this.So(config.ListenSocketMode, should.Equal, os.FileMode(0660))
+}
+func (this *ListenConfigFixture) TestTLSParameter_CertificateLoaded() {
+	certificate, key := writeTestCertificate(this.directory)
+
+	config := this.apply(Options.ListenAddress("tcp://0.0.0.0:8443?tls=" + certificate + "," + key))
+
+	this.So(config.ListenAddressError, should.BeNil)
+	if this.So(config.TLSConfig, should.NotBeNil) {
+		this.So(config.TLSConfig.Certificates, should.HaveLength, 1)
+	}
+}
+func (this *ListenConfigFixture) TestTLSParameter_CertificateLoadedOnceAcrossApplications() {
+	certificate, key := writeTestCertificate(this.directory)
+	item := Options.ListenAddress("tcp://0.0.0.0:8443?tls=" + certificate + "," + key)
+
+	first := this.apply(item)
+	_ = os.Remove(certificate)
+	second := this.apply(item)
+
+	this.So(second.ListenAddressError, should.BeNil)
+	this.So(second.TLSConfig == first.TLSConfig, should.BeTrue)
+}
+func (this *ListenConfigFixture) TestDuplicateParameter_Rejected() {
+	config := this.apply(Options.ListenAddress("127.0.0.1:8080?backlog=16&backlog=32"))
+
+	this.So(errors.Is(config.ListenAddressError, ErrInvalidSetting), should.BeTrue)
+	this.So(config.ListenAddressError.Error(), should.ContainSubstring, "[backlog]: specified 2 times")
+}
+func (this *ListenConfigFixture) TestInvalidParameters_EveryParameterReported() {
+	options := []option{Options.ListenAddress("tcp://0.0.0.0:8443?mode=0600&backlog=-1&tls=missing&color=blue")}
+
+	config := this.apply(options...)
+	err := Validate(options...)
+
+	this.So(errors.Is(config.ListenAddressError, ErrInvalidSetting), should.BeTrue)
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "[mode]: only supported for UNIX domain sockets")
+	this.So(err.Error(), should.ContainSubstring, "[backlog]: must be positive")
+	this.So(err.Error(), should.ContainSubstring, "[tls]: expected")
+	this.So(err.Error(), should.ContainSubstring, "[color]: unknown parameter")
+}
+func (this *ListenConfigFixture) TestInvalidAddress_ListenReportsNotReady() {
+	ready := make(chan bool, 1)
+	server := New(Options.ListenAddress("127.0.0.1:0?colour=blue"), Options.ListenReady(func(value bool) { ready <- value }))
+
+	go func() { _ = server.Close() }()
+	server.Listen()
+
+	this.So(<-ready, should.BeFalse)
+}
+func (this *ListenConfigFixture) TestSocketTuningQuery_ListenerSettingsApplied() {
+	config := this.apply(Options.ListenAddress("tcp6://[::]:8443?keepaliveinterval=5s&keepalivecount=3&nodelay=false" +
+		"&fastopen=256&deferaccept=2s&rcvbuf=65536&sndbuf=32768&usertimeout=30s&v6only=true"))
+
+	this.So(config.ListenAddressError, should.BeNil)
+	this.So(config.ListenKeepAliveInterval, should.Equal, time.Second*5)
+	this.So(config.ListenKeepAliveCount, should.Equal, 3)
+	this.So(config.ListenNoDelay, should.BeFalse)
+	this.So(config.ListenFastOpen, should.Equal, 256)
+	this.So(config.ListenDeferAccept, should.Equal, time.Second*2)
+	this.So(config.ListenReceiveBuffer, should.Equal, 65536)
+	this.So(config.ListenSendBuffer, should.Equal, 32768)
+	this.So(config.ListenUserTimeout, should.Equal, time.Second*30)
+	this.So(config.ListenIPv6Only, should.BeTrue)
+}
+func (this *ListenConfigFixture) TestNegativeKeepAlive_KeepAliveDisabled() {
+	config := this.apply(Options.ListenKeepAlive(-1))
+
+	this.So(config.ListenConfig.(*socketListenConfig).KeepAlive, should.BeLessThan, 0)
+	this.So(config.ListenConfig.(*socketListenConfig).KeepAliveConfig.Enable, should.BeFalse)
+}
+func (this *ListenConfigFixture) apply(options ...option) (config configuration) {
+	Options.apply(options...)(&config)
+	return config
+}
@@ -0,0 +1,65 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+func (this *socketListenConfig) listenBacklog(listener net.Listener) error {
+	conn, ok := listener.(syscall.Conn)
+	if !ok {
+		return errors.New("listener does not expose its socket")
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// calling listen(2) again on a listening socket only updates the length of the pending connection queue
+	var listenErr error
+	if err = raw.Control(func(descriptor uintptr) { listenErr = syscall.Listen(int(descriptor), this.backlog) }); err != nil {
+		return err
+	}
+	return listenErr
+}
+
+// control applies every socket option to the listening socket prior to bind. Failure to apply an option which was
+// explicitly configured prevents listening, except for SO_REUSEPORT which is enabled on a best-effort basis.
+func (this socketOptions) control(network, _ string, conn syscall.RawConn) error {
+	var errs []error
+	err := conn.Control(func(descriptor uintptr) {
+		socket := int(descriptor)
+		set := func(name string, level, option, value int) {
+			if err := syscall.SetsockoptInt(socket, level, option, value); err != nil {
+				errs = append(errs, fmt.Errorf("unable to set %s: %w", name, err))
+			}
+		}
+
+		if this.reusePort {
+			_ = syscall.SetsockoptInt(socket, syscall.SOL_SOCKET, socketReusePort, 1)
+		}
+		if this.receiveBuffer > 0 {
+			set("SO_RCVBUF", syscall.SOL_SOCKET, syscall.SO_RCVBUF, this.receiveBuffer)
+		}
+		if this.sendBuffer > 0 {
+			set("SO_SNDBUF", syscall.SOL_SOCKET, syscall.SO_SNDBUF, this.sendBuffer)
+		}
+
+		if !strings.HasPrefix(network, "tcp") {
+			return // remaining options are specific to TCP
+		}
+
+		if this.ipv6Only && strings.HasSuffix(network, "6") {
+			set("IPV6_V6ONLY", syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, 1)
+		}
+		errs = append(errs, this.controlTCP(set)...)
+	})
+
+	return errors.Join(append(errs, err)...)
+}
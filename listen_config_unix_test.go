@@ -0,0 +1,25 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/smarty/gunit/assert/should"
+)
+
+func (this *ListenConfigFixture) TestBindUnixSocket_BacklogAndModeApplied() {
+	path := filepath.Join(this.directory, "app.sock")
+	config := this.apply(Options.ListenAddress("unix://"+path+"?mode=0600&backlog=8"), Options.ListenReusePort(false))
+
+	listener, err := config.ListenConfig.Listen(context.Background(), config.ListenNetwork, config.ListenAddress)
+	if !this.So(err, should.BeNil) {
+		return
+	}
+	defer func() { _ = listener.Close() }()
+
+	info, _ := os.Stat(path)
+	this.So(info.Mode()&os.ModePerm, should.Equal, os.FileMode(0600))
+}
@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+func (this *socketListenConfig) listenBacklog(net.Listener) error {
+	return errUnsupportedSocketOption // the backlog can't be changed once the socket is listening
+}
+
+// control applies every socket option supported by Windows to the listening socket prior to bind. Windows has no
+// equivalent of SO_REUSEPORT, which is enabled on a best-effort basis elsewhere and is therefore skipped.
+func (this socketOptions) control(network, _ string, conn syscall.RawConn) error {
+	var errs []error
+	err := conn.Control(func(descriptor uintptr) {
+		socket := syscall.Handle(descriptor)
+		set := func(name string, level, option, value int) {
+			if err := syscall.SetsockoptInt(socket, level, option, value); err != nil {
+				errs = append(errs, fmt.Errorf("unable to set %s: %w", name, err))
+			}
+		}
+
+		if this.receiveBuffer > 0 {
+			set("SO_RCVBUF", syscall.SOL_SOCKET, syscall.SO_RCVBUF, this.receiveBuffer)
+		}
+		if this.sendBuffer > 0 {
+			set("SO_SNDBUF", syscall.SOL_SOCKET, syscall.SO_SNDBUF, this.sendBuffer)
+		}
+
+		if !strings.HasPrefix(network, "tcp") {
+			return // remaining options are specific to TCP
+		}
+
+		if this.ipv6Only && strings.HasSuffix(network, "6") {
+			set("IPV6_V6ONLY", syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, 1)
+		}
+		errs = append(errs, this.controlTCP(set)...)
+	})
+
+	return errors.Join(append(errs, err)...)
+}
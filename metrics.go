@@ -0,0 +1,229 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics is a monitor which aggregates the telemetry reported by the server and, as an http.Handler, exposes it using
+// the Prometheus text exposition format. Provide it to Options.Monitor and route a path (e.g. "/metrics") to it:
+//
+//	metrics := httpserver.NewMetrics()
+//	server := httpserver.New(httpserver.Options.Monitor(metrics), ...)
+//	router.Handle("/metrics", metrics)
+//
+// Custom monitors wanting the same metrics may embed *Metrics.
+type Metrics struct {
+	mutex       sync.Mutex
+	buckets     []float64
+	requests    map[requestMetricKey]uint64
+	durations   map[string]*histogram
+	inFlight    int64
+	connections map[net.Conn]http.ConnState
+	panics      map[string]uint64
+	shutdown    map[ShutdownPhase]time.Duration
+	serverLogs  map[serverLogMetricKey]uint64
+}
+type requestMetricKey struct {
+	method string
+	status string
+}
+type serverLogMetricKey struct {
+	class      ServerLogClass
+	suppressed bool
+}
+type histogram struct {
+	counts []uint64 // cumulative count for each bucket
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics creates Metrics using the upper bounds (in seconds) of the request duration histogram buckets provided,
+// or the buckets used by default in Prometheus client libraries if none are provided.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = defaultMetricsBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &Metrics{
+		buckets:     slices.Compact(buckets),
+		requests:    make(map[requestMetricKey]uint64),
+		durations:   make(map[string]*histogram),
+		connections: make(map[net.Conn]http.ConnState),
+		panics:      map[string]uint64{"recovered": 0, "ignored": 0, "aborted": 0},
+		shutdown:    make(map[ShutdownPhase]time.Duration),
+		serverLogs:  make(map[serverLogMetricKey]uint64),
+	}
+}
+
+func (this *Metrics) RequestStarted(*http.Request) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.inFlight++
+}
+func (this *Metrics) RequestFinished(request *http.Request, status int, _ int64, duration time.Duration) {
+	method := metricsMethod(request.Method)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.inFlight--
+	this.requests[requestMetricKey{method: method, status: metricsStatusClass(status)}]++
+
+	durations, found := this.durations[method]
+	if !found {
+		durations = &histogram{counts: make([]uint64, len(this.buckets))}
+		this.durations[method] = durations
+	}
+	durations.observe(this.buckets, duration.Seconds())
+}
+func (this *Metrics) ConnectionStateChanged(conn net.Conn, state http.ConnState) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if state == http.StateClosed || state == http.StateHijacked {
+		delete(this.connections, conn)
+	} else {
+		this.connections[conn] = state
+	}
+}
+func (this *Metrics) PanicRecovered(*http.Request, any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.panics["recovered"]++
+}
+func (this *Metrics) PanicIgnored(*http.Request, any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.panics["ignored"]++
+}
+func (this *Metrics) PanicAborted(*http.Request, any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.panics["aborted"]++
+}
+func (this *Metrics) ShutdownPhaseEntered(phase ShutdownPhase, elapsed time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.shutdown[phase] = elapsed
+}
+func (this *Metrics) ServerLogged(class ServerLogClass, suppressed bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.serverLogs[serverLogMetricKey{class: class, suppressed: suppressed}]++
+}
+
+func (this *histogram) observe(buckets []float64, value float64) {
+	for i, bound := range buckets {
+		if value <= bound {
+			this.counts[i]++
+		}
+	}
+	this.count++
+	this.sum += value
+}
+
+func (this *Metrics) ServeHTTP(response http.ResponseWriter, _ *http.Request) {
+	response.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = this.WriteTo(response)
+}
+
+// WriteTo writes every metric using the Prometheus text exposition format.
+func (this *Metrics) WriteTo(writer io.Writer) (int64, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	var builder strings.Builder
+
+	writeMetricsHeader(&builder, "http_server_requests_total", "counter", "Requests handled by method and status class.")
+	for _, key := range sortedKeys(this.requests, func(a, b requestMetricKey) int {
+		return strings.Compare(a.method+a.status, b.method+b.status)
+	}) {
+		_, _ = fmt.Fprintf(&builder, "http_server_requests_total{method=%q,code=%q} %d\n", key.method, key.status, this.requests[key])
+	}
+
+	writeMetricsHeader(&builder, "http_server_request_duration_seconds", "histogram", "Time taken to handle requests by method.")
+	for _, method := range sortedKeys(this.durations, strings.Compare) {
+		durations := this.durations[method]
+		for i, bound := range this.buckets {
+			_, _ = fmt.Fprintf(&builder, "http_server_request_duration_seconds_bucket{method=%q,le=%q} %d\n", method, formatMetricsFloat(bound), durations.counts[i])
+		}
+		_, _ = fmt.Fprintf(&builder, "http_server_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, durations.count)
+		_, _ = fmt.Fprintf(&builder, "http_server_request_duration_seconds_sum{method=%q} %s\n", method, formatMetricsFloat(durations.sum))
+		_, _ = fmt.Fprintf(&builder, "http_server_request_duration_seconds_count{method=%q} %d\n", method, durations.count)
+	}
+
+	writeMetricsHeader(&builder, "http_server_requests_in_flight", "gauge", "Requests currently being handled.")
+	_, _ = fmt.Fprintf(&builder, "http_server_requests_in_flight %d\n", this.inFlight)
+
+	connections := map[http.ConnState]int{http.StateNew: 0, http.StateActive: 0, http.StateIdle: 0}
+	for _, state := range this.connections {
+		connections[state]++
+	}
+	writeMetricsHeader(&builder, "http_server_connections", "gauge", "Open connections by state.")
+	for _, state := range []http.ConnState{http.StateNew, http.StateActive, http.StateIdle} {
+		_, _ = fmt.Fprintf(&builder, "http_server_connections{state=%q} %d\n", state, connections[state])
+	}
+
+	writeMetricsHeader(&builder, "http_server_panics_total", "counter", "Panics recovered while handling requests by outcome.")
+	for _, outcome := range sortedKeys(this.panics, strings.Compare) {
+		_, _ = fmt.Fprintf(&builder, "http_server_panics_total{outcome=%q} %d\n", outcome, this.panics[outcome])
+	}
+
+	writeMetricsHeader(&builder, "http_server_shutdown_duration_seconds", "gauge", "Time elapsed from the start of shutdown until each phase was reached.")
+	for _, phase := range sortedKeys(this.shutdown, func(a, b ShutdownPhase) int { return int(a) - int(b) }) {
+		_, _ = fmt.Fprintf(&builder, "http_server_shutdown_duration_seconds{phase=%q} %s\n", phase, formatMetricsFloat(this.shutdown[phase].Seconds()))
+	}
+
+	writeMetricsHeader(&builder, "http_server_error_log_messages_total", "counter", "Messages written by the http.Server to its error log by class.")
+	for _, key := range sortedKeys(this.serverLogs, func(a, b serverLogMetricKey) int {
+		return strings.Compare(fmt.Sprint(a.class, a.suppressed), fmt.Sprint(b.class, b.suppressed))
+	}) {
+		_, _ = fmt.Fprintf(&builder, "http_server_error_log_messages_total{class=%q,suppressed=\"%t\"} %d\n", key.class, key.suppressed, this.serverLogs[key])
+	}
+
+	written, err := io.WriteString(writer, builder.String())
+	return int64(written), err
+}
+
+func writeMetricsHeader(builder *strings.Builder, name, kind, help string) {
+	_, _ = fmt.Fprintf(builder, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+func formatMetricsFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+func sortedKeys[K comparable, V any](values map[K]V, compare func(K, K) int) []K {
+	keys := make([]K, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, compare)
+	return keys
+}
+
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER" // bounds the number of time series created by arbitrary methods
+	}
+}
+func metricsStatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown" // hijacked or the handler panicked without recovery
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+var defaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestMetricsFixture(t *testing.T) {
+	gunit.Run(new(MetricsFixture), t)
+}
+
+type MetricsFixture struct {
+	*gunit.Fixture
+
+	metrics *Metrics
+}
+
+func (this *MetricsFixture) Setup() {
+	this.metrics = NewMetrics(1, 0.1, 1)
+}
+
+func (this *MetricsFixture) TestRequests_CountedByMethodAndStatusClassWithHistogram() {
+	this.metrics.RequestStarted(nil)
+	this.metrics.RequestStarted(nil)
+	this.metrics.RequestFinished(httptest.NewRequest("GET", "/", nil), 200, 0, time.Millisecond*50)
+	this.metrics.RequestFinished(httptest.NewRequest("GET", "/", nil), 404, 0, time.Millisecond*500)
+	this.metrics.RequestStarted(nil)
+	this.metrics.RequestFinished(httptest.NewRequest("BREW", "/", nil), 0, 0, time.Second*2)
+
+	output := this.scrape()
+
+	this.So(output, should.ContainSubstring, `http_server_requests_total{method="GET",code="2xx"} 1`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_requests_total{method="GET",code="4xx"} 1`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_requests_total{method="OTHER",code="unknown"} 1`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_request_duration_seconds_bucket{method="GET",le="0.1"} 1`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_request_duration_seconds_bucket{method="GET",le="1"} 2`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_request_duration_seconds_bucket{method="GET",le="+Inf"} 2`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_request_duration_seconds_sum{method="GET"} 0.55`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_request_duration_seconds_count{method="OTHER"} 1`+"\n")
+	this.So(output, should.ContainSubstring, "http_server_requests_in_flight 0\n")
+	this.So(strings.Count(output, `le="1"`), should.Equal, 2) // buckets deduplicated
+}
+func (this *MetricsFixture) TestConnections_GaugedByCurrentState() {
+	first, second := net.Pipe()
+	this.metrics.ConnectionStateChanged(first, http.StateNew)
+	this.metrics.ConnectionStateChanged(first, http.StateActive)
+	this.metrics.ConnectionStateChanged(second, http.StateNew)
+	this.metrics.ConnectionStateChanged(second, http.StateActive)
+	this.metrics.ConnectionStateChanged(second, http.StateIdle)
+	this.metrics.ConnectionStateChanged(first, http.StateClosed)
+
+	output := this.scrape()
+
+	this.So(output, should.ContainSubstring, `http_server_connections{state="new"} 0`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_connections{state="active"} 0`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_connections{state="idle"} 1`+"\n")
+}
+func (this *MetricsFixture) TestPanicsAndShutdown_Exposed() {
+	this.metrics.PanicRecovered(nil, "boink")
+	this.metrics.PanicIgnored(nil, context.Canceled)
+	this.metrics.PanicIgnored(nil, context.Canceled)
+	this.metrics.ShutdownPhaseEntered(ShutdownDrained, time.Millisecond*1500)
+
+	output := this.scrape()
+
+	this.So(output, should.ContainSubstring, `http_server_panics_total{outcome="recovered"} 1`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_panics_total{outcome="ignored"} 2`+"\n")
+	this.So(output, should.ContainSubstring, `http_server_shutdown_duration_seconds{phase="drained"} 1.5`+"\n")
+}
+func (this *MetricsFixture) TestServedThroughServer_IgnoredPanicsReported() {
+	var config configuration
+	Options.apply(Options.Monitor(this.metrics), Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(context.Canceled)
+	})))(&config)
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = config.HTTPServer.(*http.Server)
+	server.Start()
+	defer server.Close()
+
+	response, _ := server.Client().Get(server.URL)
+	_ = response.Body.Close()
+
+	this.So(this.scrape(), should.ContainSubstring, `http_server_panics_total{outcome="ignored"} 1`+"\n")
+}
+
+func (this *MetricsFixture) scrape() string {
+	recorder := httptest.NewRecorder()
+	this.metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	this.So(recorder.Header().Get("Content-Type"), should.StartWith, "text/plain; version=0.0.4")
+	raw, _ := io.ReadAll(recorder.Body)
+	return string(raw)
+}
@@ -0,0 +1,78 @@
+package httpserver
+
+import "net/http"
+
+// PanicSeverity determines how a recovered panic is logged and reported.
+type PanicSeverity uint8
+
+const (
+	// PanicSeverityError logs the panic in full (including the stack trace and request dump) and counts it toward the
+	// PanicStormThreshold.
+	PanicSeverityError PanicSeverity = iota
+
+	// PanicSeverityWarning logs the panic at the warning level without a stack trace.
+	PanicSeverityWarning
+
+	// PanicSeverityInfo logs the panic at the informational level without a stack trace.
+	PanicSeverityInfo
+
+	// PanicSeverityIgnored reports the panic through PanicIgnored (if implemented by the monitor) rather than
+	// PanicRecovered and doesn't log it.
+	PanicSeverityIgnored
+)
+
+func (this PanicSeverity) String() string {
+	switch this {
+	case PanicSeverityError:
+		return "error"
+	case PanicSeverityWarning:
+		return "warning"
+	case PanicSeverityInfo:
+		return "info"
+	case PanicSeverityIgnored:
+		return "ignored"
+	default:
+		return "unknown"
+	}
+}
+
+// PanicClassification determines the severity of a recovered panic along with the status and detail written to the
+// client (see PanicDetails). A StatusCode outside the 4xx and 5xx ranges is treated as 500.
+type PanicClassification struct {
+	Severity   PanicSeverity
+	StatusCode int
+	Detail     string
+}
+
+// PanicClassifier classifies each recovered panic given the request being handled (see Options.PanicClassifier). The
+// classification provided is the one determined by default, i.e. ignored when matching one of the IgnoredErrors, the
+// status and message of a StatusError, otherwise an error, which may be returned unchanged.
+type PanicClassifier func(recovered any, request *http.Request, classification PanicClassification) PanicClassification
+
+func (this *recoveryHandler) classify(recovered any, request *http.Request) PanicClassification {
+	classification := PanicClassification{Severity: PanicSeverityError, StatusCode: http.StatusInternalServerError}
+	if this.isIgnoredError(recovered) {
+		classification.Severity = PanicSeverityIgnored
+	} else if statusError, ok := asStatusError(recovered); ok {
+		classification = PanicClassification{Severity: PanicSeverityWarning, StatusCode: statusError.status(), Detail: statusError.Message}
+		if classification.StatusCode < http.StatusInternalServerError {
+			classification.Severity = PanicSeverityInfo
+		}
+	}
+
+	if classifier := this.settings.Load().classifier; classifier != nil {
+		classification = classifier(recovered, request, classification)
+	}
+
+	if classification.Severity > PanicSeverityIgnored {
+		classification.Severity = PanicSeverityError
+	}
+	classification.StatusCode = panicStatus(classification.StatusCode)
+	return classification
+}
+func panicStatus(statusCode int) int {
+	if statusCode < 400 || statusCode > 599 {
+		return http.StatusInternalServerError
+	}
+	return statusCode
+}
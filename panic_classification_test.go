@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestPanicClassificationFixture(t *testing.T) {
+	gunit.Run(new(PanicClassificationFixture), t)
+}
+
+type PanicClassificationFixture struct {
+	*gunit.Fixture
+
+	classifier PanicClassifier
+	received   []PanicClassification
+	recovered  int
+	ignored    int
+	logged     []string
+}
+
+func (this *PanicClassificationFixture) serve(value any, ctx context.Context) *httptest.ResponseRecorder {
+	var config configuration
+	Options.apply(
+		Options.Monitor(this),
+		Options.Logger(this),
+		Options.PanicClassifier(this.classify),
+		Options.Handler(panickingHandler(value)),
+	)(&config)
+	recorder := httptest.NewRecorder()
+	config.Handler.ServeHTTP(recorder, httptest.NewRequestWithContext(ctx, "GET", "/", nil))
+	return recorder
+}
+func (this *PanicClassificationFixture) classify(recovered any, request *http.Request, classification PanicClassification) PanicClassification {
+	this.received = append(this.received, classification)
+	if this.classifier == nil {
+		return classification
+	}
+	return this.classifier(recovered, request, classification)
+}
+
+func (this *PanicClassificationFixture) TestDefaultClassificationProvided() {
+	this.serve(errors.New("boink"), context.Background())
+	this.serve(fmt.Errorf("inner: %w", context.Canceled), context.Background())
+	this.serve(StatusError{StatusCode: 404, Message: "missing"}, context.Background())
+	this.serve(StatusError{StatusCode: 503}, context.Background())
+
+	this.So(this.received, should.Equal, []PanicClassification{
+		{Severity: PanicSeverityError, StatusCode: 500},
+		{Severity: PanicSeverityIgnored, StatusCode: 500},
+		{Severity: PanicSeverityInfo, StatusCode: 404, Detail: "missing"},
+		{Severity: PanicSeverityWarning, StatusCode: 503},
+	})
+}
+func (this *PanicClassificationFixture) TestContextCanceledIgnoredOnlyWhenClientDisconnected() {
+	this.classifier = func(recovered any, request *http.Request, classification PanicClassification) PanicClassification {
+		if err, ok := recovered.(error); ok && errors.Is(err, context.Canceled) && request.Context().Err() == nil {
+			return PanicClassification{Severity: PanicSeverityError, StatusCode: http.StatusBadGateway}
+		}
+		return classification
+	}
+	disconnected, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	this.serve(context.Canceled, disconnected)
+	this.So(this.ignored, should.Equal, 1)
+	this.So(this.logged, should.BeEmpty)
+
+	recorder := this.serve(context.Canceled, context.Background())
+	this.So(recorder.Code, should.Equal, http.StatusBadGateway)
+	this.So(this.recovered, should.Equal, 1)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.StartWith, "[ERROR] Recovered panic: context canceled")
+		this.So(this.logged[0], should.ContainSubstring, "_test.go:")
+	}
+}
+func (this *PanicClassificationFixture) TestWarningLoggedWithoutStackAndRespondsWithDetail() {
+	this.classifier = func(any, *http.Request, PanicClassification) PanicClassification {
+		return PanicClassification{Severity: PanicSeverityWarning, StatusCode: http.StatusServiceUnavailable, Detail: "overloaded"}
+	}
+
+	recorder := this.serve("boink", context.Background())
+
+	this.So(recorder.Code, should.Equal, http.StatusServiceUnavailable)
+	this.So(recorder.Body.String(), should.StartWith, "Service Unavailable\noverloaded\n")
+	this.So(this.recovered, should.Equal, 1)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.StartWith, "[WARN] Recovered panic: boink [correlation ID: ")
+		this.So(this.logged[0], should.NotContainSubstring, "_test.go:")
+	}
+}
+func (this *PanicClassificationFixture) TestUnknownSeverityAndStatus_TreatedAsError() {
+	this.classifier = func(any, *http.Request, PanicClassification) PanicClassification {
+		return PanicClassification{Severity: 42, StatusCode: 200}
+	}
+
+	recorder := this.serve("boink", context.Background())
+
+	this.So(recorder.Code, should.Equal, http.StatusInternalServerError)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.StartWith, "[ERROR] Recovered panic: boink")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (this *PanicClassificationFixture) PanicRecovered(*http.Request, any) { this.recovered++ }
+func (this *PanicClassificationFixture) PanicIgnored(*http.Request, any)   { this.ignored++ }
+func (this *PanicClassificationFixture) Printf(format string, args ...any) {
+	this.logged = append(this.logged, fmt.Sprintf(format, args...))
+}
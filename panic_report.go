@@ -0,0 +1,174 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PanicReport describes a recovered panic for delivery to an error tracker, and is provided to a monitor which also
+// implements PanicReported(PanicReport). It's serialized as JSON using the field names of its tags.
+type PanicReport struct {
+	Value         any          `json:"-"`
+	Message       string       `json:"message"`
+	Type          string       `json:"type"`
+	ErrorChain    []PanicError `json:"error_chain,omitempty"`
+	Frames        []StackFrame `json:"frames"`
+	Fingerprint   string       `json:"fingerprint"`
+	CorrelationID string       `json:"correlation_id"`
+	Aborted       bool         `json:"aborted"`
+	Time          time.Time    `json:"time"`
+	Request       PanicRequest `json:"request"`
+}
+
+// PanicError is one of the errors wrapped by a recovered error, outermost first.
+type PanicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// StackFrame is a function call of the goroutine which panicked, starting with the function which raised the panic. The
+// frames of the runtime raising the panic and those recovering it are excluded.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// PanicRequest summarizes the request being handled when the panic occurred. The query of the URL is redacted according
+// to Options.DumpRedactQuery, and the RequestID is empty unless Options.RequestID is enabled.
+type PanicRequest struct {
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+	Protocol      string `json:"protocol"`
+	Host          string `json:"host"`
+	RemoteAddress string `json:"remote_address"`
+	UserAgent     string `json:"user_agent,omitempty"`
+	ContentLength int64  `json:"content_length"`
+	RequestID     string `json:"request_id,omitempty"`
+}
+
+func (this StackFrame) String() string {
+	return fmt.Sprintf("%s %s:%d", this.Function, this.File, this.Line)
+}
+
+// newPanicReport must be called while the panic is being recovered such that the stack of the goroutine which panicked
+// is available.
+func newPanicReport(recovered any, request *http.Request, correlationID string, aborted bool, redaction requestRedaction) PanicReport {
+	this := PanicReport{
+		Value:         recovered,
+		Message:       fmt.Sprint(recovered),
+		Type:          fmt.Sprintf("%T", recovered),
+		Frames:        panicFrames(),
+		CorrelationID: correlationID,
+		Aborted:       aborted,
+		Time:          time.Now().UTC(),
+		Request: PanicRequest{
+			Method:        request.Method,
+			URL:           request.URL.Path,
+			Protocol:      request.Proto,
+			Host:          request.Host,
+			RemoteAddress: request.RemoteAddr,
+			UserAgent:     request.UserAgent(),
+			ContentLength: request.ContentLength,
+			RequestID:     RequestIDFromContext(request.Context()),
+		},
+	}
+
+	if query := redactPairs(request.URL.RawQuery, redaction.queryNames); len(query) > 0 {
+		this.Request.URL += "?" + query
+	}
+
+	if err, ok := recovered.(error); ok {
+		this.ErrorChain = errorChain(err, nil)
+	}
+
+	this.Fingerprint = this.fingerprint()
+	return this
+}
+
+// fingerprint groups panics of the same type raised through the same sequence of functions. Line numbers are excluded
+// such that the fingerprint remains stable across unrelated changes to the source. Frames serving the request (i.e. of
+// net/http or of this package) preceding the first frame of the application, e.g. of a panic raised by net/http for an
+// invalid status code, are skipped, as are the frames from the next one serving the request such that the fingerprint
+// doesn't depend upon the protocol or the options enabled (e.g. access logging adds a frame).
+func (this PanicReport) fingerprint() string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintln(hash, this.Type)
+	start := max(slices.IndexFunc(this.Frames, func(frame StackFrame) bool { return !isServingFrame(frame) }), 0)
+	for i, frame := range this.Frames[start:] {
+		if i > 0 && isServingFrame(frame) {
+			break
+		}
+		_, _ = fmt.Fprintln(hash, frame.Function)
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+func isServingFrame(frame StackFrame) bool {
+	return strings.HasPrefix(frame.Function, "net/http.") || strings.HasPrefix(frame.Function, packagePath+".")
+}
+
+// stack formats the frames in the manner of a goroutine stack trace.
+func (this PanicReport) stack() string {
+	var builder strings.Builder
+	for _, frame := range this.Frames {
+		_, _ = fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return builder.String()
+}
+
+// location identifies where the panic was raised.
+func (this PanicReport) location() string {
+	if len(this.Frames) == 0 {
+		return "unknown"
+	}
+	return this.Frames[0].String()
+}
+
+func errorChain(err error, chain []PanicError) []PanicError {
+	for err != nil {
+		chain = append(chain, PanicError{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, inner := range joined.Unwrap() {
+				chain = errorChain(inner, chain)
+			}
+			return chain
+		}
+		err = errors.Unwrap(err)
+	}
+	return chain
+}
+
+var packagePath = reflect.TypeFor[recoveryHandler]().PkgPath()
+
+// panicFrames returns the frames of the goroutine below runtime.gopanic, i.e. excluding the deferred functions recovering
+// the panic, and excluding the runtime functions which raised it (e.g. runtime.panicIndex).
+func panicFrames() (parsed []StackFrame) {
+	callers := make([]uintptr, 64)
+	count := runtime.Callers(2, callers)
+	for ; count == len(callers); count = runtime.Callers(2, callers) {
+		callers = make([]uintptr, len(callers)*2) // the stack may be deeper still
+	}
+	frames := runtime.CallersFrames(callers[:count])
+
+	panicking := false
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+
+		if !panicking {
+			panicking = frame.Function == "runtime.gopanic"
+		} else if (len(parsed) > 0 || !strings.HasPrefix(frame.Function, "runtime.")) && frame.Function != "runtime.goexit" {
+			parsed = append(parsed, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+	}
+
+	return parsed
+}
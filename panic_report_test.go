@@ -0,0 +1,155 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestPanicReportFixture(t *testing.T) {
+	gunit.Run(new(PanicReportFixture), t)
+}
+
+type PanicReportFixture struct {
+	*gunit.Fixture
+
+	reports []PanicReport
+}
+
+func (this *PanicReportFixture) serve(handler http.HandlerFunc, target string, options ...option) {
+	var config configuration
+	Options.apply(append(options, Options.Monitor(this), Options.Handler(handler))...)(&config)
+	request := httptest.NewRequest("POST", target, strings.NewReader("body"))
+	request.Header.Set("User-Agent", "test-agent")
+	config.Handler.ServeHTTP(httptest.NewRecorder(), request)
+}
+
+func (this *PanicReportFixture) TestReportDescribesPanic() {
+	cause := errors.Join(io.ErrUnexpectedEOF, fmt.Errorf("decoding: %w", io.EOF))
+	this.serve(panickingHandler(fmt.Errorf("loading: %w", cause)), "/path?id=1&token=secret")
+
+	if !this.So(this.reports, should.HaveLength, 1) {
+		return
+	}
+	report := this.reports[0]
+	this.So(report.Message, should.Equal, "loading: unexpected EOF\ndecoding: EOF")
+	this.So(report.Type, should.Equal, "*fmt.wrapError")
+	this.So(report.ErrorChain, should.Equal, []PanicError{
+		{Type: "*fmt.wrapError", Message: "loading: unexpected EOF\ndecoding: EOF"},
+		{Type: "*errors.joinError", Message: "unexpected EOF\ndecoding: EOF"},
+		{Type: "*errors.errorString", Message: "unexpected EOF"},
+		{Type: "*fmt.wrapError", Message: "decoding: EOF"},
+		{Type: "*errors.errorString", Message: "EOF"},
+	})
+	this.So(report.Fingerprint, should.HaveLength, 32)
+	this.So(report.CorrelationID, should.HaveLength, 32)
+	this.So(report.Aborted, should.BeFalse)
+	this.So(report.Time.IsZero(), should.BeFalse)
+	this.So(report.Request, should.Equal, PanicRequest{
+		Method:        "POST",
+		URL:           "/path?id=1&token=[REDACTED]",
+		Protocol:      "HTTP/1.1",
+		Host:          "example.com",
+		RemoteAddress: "192.0.2.1:1234",
+		UserAgent:     "test-agent",
+		ContentLength: 4,
+	})
+}
+func (this *PanicReportFixture) TestFramesStartWherePanicRaisedAndExcludeRecovery() {
+	this.serve(func(http.ResponseWriter, *http.Request) {
+		var values []int
+		_ = values[1] // runtime error raised through runtime.goPanicIndex
+	}, "/")
+
+	frames := this.reports[0].Frames
+	this.So(frames[0].Function, should.StartWith, "github.com/smarty/httpserver/v2.(*PanicReportFixture).TestFramesStartWherePanicRaisedAndExcludeRecovery")
+	this.So(frames[0].File, should.EndWith, "panic_report_test.go")
+	this.So(frames[0].Line, should.BeGreaterThan, 0)
+	for _, frame := range frames {
+		this.So(frame.Function, should.NotStartWith, "runtime.")
+		this.So(frame.Function, should.NotContainSubstring, "finally")
+		this.So(frame.Function, should.NotContainSubstring, "logRecovery")
+	}
+}
+func (this *PanicReportFixture) TestFingerprintGroupsPanicsOfSameTypeFromSameFunctions() {
+	this.serve(panickingHandler("a"), "/")
+	this.serve(panickingHandler("b"), "/")
+	this.serve(panickingHandler(errors.New("c")), "/")
+
+	this.So(this.reports[0].Fingerprint, should.Equal, this.reports[1].Fingerprint)
+	this.So(this.reports[0].Fingerprint, should.NotEqual, this.reports[2].Fingerprint)
+}
+func (this *PanicReportFixture) TestFingerprintIndependentOfServingMiddleware() {
+	handler := panickingHandler("a")
+	this.serve(handler, "/")
+	this.serve(handler, "/", Options.AccessLogger(&nop{}), Options.RequestID(true))
+
+	this.So(len(this.reports[0].Frames), should.BeLessThan, len(this.reports[1].Frames))
+	this.So(this.reports[0].Fingerprint, should.Equal, this.reports[1].Fingerprint)
+}
+func (this *PanicReportFixture) TestFingerprintOfPanicRaisedWhileServing_ApplicationFramesHashed() {
+	frames := func(application ...string) (frames []StackFrame) {
+		for _, function := range []string{"net/http.checkWriteHeaderCode", "net/http.(*response).WriteHeader", packagePath + ".(*trackingResponseWriter).WriteHeader"} {
+			frames = append(frames, StackFrame{Function: function})
+		}
+		for _, function := range application {
+			frames = append(frames, StackFrame{Function: function})
+		}
+		return append(frames, StackFrame{Function: "net/http.HandlerFunc.ServeHTTP"}, StackFrame{Function: packagePath + ".(*swapHandler).ServeHTTP"})
+	}
+
+	first := PanicReport{Type: "string", Frames: frames("example.com/app.respond", "example.com/app.first")}.fingerprint()
+	second := PanicReport{Type: "string", Frames: frames("example.com/app.respond", "example.com/app.second")}.fingerprint()
+	again := PanicReport{Type: "string", Frames: frames("example.com/app.respond", "example.com/app.first")[:6]}.fingerprint()
+
+	this.So(first, should.NotEqual, second)
+	this.So(first, should.Equal, again)
+}
+func (this *PanicReportFixture) TestDeepStack_EveryFrameReported() {
+	var recurse func(int)
+	recurse = func(depth int) {
+		if depth == 0 {
+			panic("deep")
+		}
+		recurse(depth - 1)
+	}
+	this.serve(func(http.ResponseWriter, *http.Request) { recurse(200) }, "/")
+
+	frames := this.reports[0].Frames
+	this.So(len(frames), should.BeGreaterThan, 200)
+	this.So(frames[len(frames)-1].Function, should.Equal, "testing.tRunner")
+}
+func (this *PanicReportFixture) TestSerializedAsJSON() {
+	this.serve(panickingHandler("boink"), "/")
+
+	raw, err := json.Marshal(this.reports[0])
+	var decoded map[string]any
+	_ = json.Unmarshal(raw, &decoded)
+
+	this.So(err, should.BeNil)
+	this.So(decoded["message"], should.Equal, "boink")
+	this.So(decoded["type"], should.Equal, "string")
+	this.So(decoded["fingerprint"], should.Equal, this.reports[0].Fingerprint)
+	this.So(decoded["frames"].([]any)[0].(map[string]any)["function"], should.Equal, this.reports[0].Frames[0].Function)
+	this.So(decoded["request"].(map[string]any)["method"], should.Equal, "POST")
+	this.So(decoded, should.NotContainKey, "error_chain")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func panickingHandler(value any) http.HandlerFunc {
+	return func(http.ResponseWriter, *http.Request) { panic(value) }
+}
+
+func (this *PanicReportFixture) PanicRecovered(*http.Request, any) {}
+func (this *PanicReportFixture) PanicReported(report PanicReport) {
+	this.reports = append(this.reports, report)
+}
@@ -0,0 +1,225 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PanicDetails describes the response to be written once a panic has been recovered. The Detail is empty unless a
+// message suitable for the client was provided. The CorrelationID is also written to the log such that the response
+// can be associated with the recovered panic.
+type PanicDetails struct {
+	StatusCode    int
+	Title         string
+	Detail        string
+	CorrelationID string
+}
+
+// StatusError may be panicked by a handler to respond with a status other than 500 (e.g. 400, 404 or 503), in which
+// case the Message (if any) is written to the client as the detail of the response. Unlike other panics it's logged
+// without a stack trace at the warning level (informational for a 4xx status). A StatusCode outside the 4xx and 5xx
+// ranges is treated as 500.
+//
+//	panic(httpserver.StatusError{StatusCode: http.StatusServiceUnavailable, Message: "try again later", Err: err})
+type StatusError struct {
+	StatusCode int
+	Message    string // public message written to the client
+	Err        error  // underlying cause, logged but not written to the client
+}
+
+func (this StatusError) Error() string {
+	text := strconv.Itoa(this.StatusCode) + " " + http.StatusText(this.StatusCode)
+	if len(this.Message) > 0 {
+		text += ": " + this.Message
+	}
+	if this.Err != nil {
+		text += ": " + this.Err.Error()
+	}
+	return text
+}
+func (this StatusError) Unwrap() error { return this.Err }
+func (this StatusError) status() int   { return panicStatus(this.StatusCode) }
+
+// PanicResponder writes the response once a panic has been recovered (see Options.PanicResponder). The MediaType is
+// the content type of the body written, which is used to select a responder by the Accept header of the request.
+type PanicResponder interface {
+	MediaType() string
+	RespondToPanic(response http.ResponseWriter, request *http.Request, details PanicDetails)
+}
+
+// NegotiatePanicResponse selects from the responders provided using the Accept header of the request, where a media
+// range such as "application/json" also matches a structured syntax suffix such as "application/problem+json". The
+// first responder is used when none is acceptable or the request has no Accept header.
+func NegotiatePanicResponse(responders ...PanicResponder) PanicResponder {
+	if len(responders) == 0 {
+		return PlainTextPanicResponder{}
+	}
+	return negotiatedPanicResponder(responders)
+}
+
+type negotiatedPanicResponder []PanicResponder
+
+func (this negotiatedPanicResponder) MediaType() string {
+	return this[0].MediaType()
+}
+func (this negotiatedPanicResponder) RespondToPanic(response http.ResponseWriter, request *http.Request, details PanicDetails) {
+	response.Header().Add("Vary", "Accept")
+	this.choose(request.Header.Values("Accept")).RespondToPanic(response, request, details)
+}
+func (this negotiatedPanicResponder) choose(accept []string) PanicResponder {
+	for _, mediaRange := range parseAccept(accept) {
+		for _, responder := range this {
+			if mediaTypeMatches(mediaRange, responder.MediaType()) {
+				return responder
+			}
+		}
+	}
+	return this[0]
+}
+
+// parseAccept returns the acceptable media ranges from most to least preferred.
+func parseAccept(values []string) []string {
+	type acceptable struct {
+		mediaRange string
+		quality    float64
+	}
+
+	var ranges []acceptable
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if raw, found := params["q"]; found {
+				if quality, err = strconv.ParseFloat(raw, 64); err != nil {
+					continue
+				}
+			}
+
+			if quality > 0 {
+				ranges = append(ranges, acceptable{mediaRange: mediaRange, quality: quality})
+			}
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b acceptable) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		default:
+			return strings.Count(a.mediaRange, "*") - strings.Count(b.mediaRange, "*") // more specific first
+		}
+	})
+
+	mediaRanges := make([]string, 0, len(ranges))
+	for _, item := range ranges {
+		mediaRanges = append(mediaRanges, item.mediaRange)
+	}
+	return mediaRanges
+}
+func mediaTypeMatches(mediaRange, mediaType string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	actualType, actualSubtype, _ := strings.Cut(mediaType, "/")
+
+	switch {
+	case rangeType == "*":
+		return true
+	case rangeType != actualType:
+		return false
+	case rangeSubtype == "*" || rangeSubtype == actualSubtype:
+		return true
+	default:
+		return strings.HasSuffix(actualSubtype, "+"+rangeSubtype) // e.g. "application/json" accepts "application/problem+json"
+	}
+}
+
+// PlainTextPanicResponder writes the title, followed by the detail and correlation ID (if any), as plain text.
+type PlainTextPanicResponder struct{}
+
+func (PlainTextPanicResponder) MediaType() string { return "text/plain; charset=utf-8" }
+func (this PlainTextPanicResponder) RespondToPanic(response http.ResponseWriter, _ *http.Request, details PanicDetails) {
+	lines := []string{details.Title}
+	if len(details.Detail) > 0 {
+		lines = append(lines, details.Detail)
+	}
+	if len(details.CorrelationID) > 0 {
+		lines = append(lines, "Correlation ID: "+details.CorrelationID)
+	}
+
+	writePanicResponse(response, this.MediaType(), details.StatusCode)
+	_, _ = io.WriteString(response, strings.Join(lines, "\n")+"\n")
+}
+
+// ProblemJSONPanicResponder writes an RFC 9457 problem details object including a "correlation_id" extension member.
+// The Type is the URI identifying the problem, "about:blank" when empty.
+type ProblemJSONPanicResponder struct {
+	Type string
+}
+
+func (ProblemJSONPanicResponder) MediaType() string { return "application/problem+json" }
+func (this ProblemJSONPanicResponder) RespondToPanic(response http.ResponseWriter, _ *http.Request, details PanicDetails) {
+	problem := struct {
+		Type          string `json:"type"`
+		Title         string `json:"title"`
+		Status        int    `json:"status"`
+		Detail        string `json:"detail,omitempty"`
+		CorrelationID string `json:"correlation_id,omitempty"`
+	}{Type: this.Type, Title: details.Title, Status: details.StatusCode, Detail: details.Detail, CorrelationID: details.CorrelationID}
+	if len(problem.Type) == 0 {
+		problem.Type = "about:blank"
+	}
+
+	writePanicResponse(response, this.MediaType(), details.StatusCode)
+	_ = json.NewEncoder(response).Encode(problem)
+}
+
+// HTMLPanicResponder renders the PanicDetails using the Template provided, or a minimal error page when nil.
+type HTMLPanicResponder struct {
+	Template *template.Template
+}
+
+func (HTMLPanicResponder) MediaType() string { return "text/html; charset=utf-8" }
+func (this HTMLPanicResponder) RespondToPanic(response http.ResponseWriter, _ *http.Request, details PanicDetails) {
+	page := this.Template
+	if page == nil {
+		page = defaultPanicPage
+	}
+
+	writePanicResponse(response, this.MediaType(), details.StatusCode)
+	_ = page.Execute(response, details)
+}
+
+func writePanicResponse(response http.ResponseWriter, contentType string, statusCode int) {
+	header := response.Header()
+	header.Del("Content-Length") // a partially written response may have declared the length of the original body
+	header.Set("Content-Type", contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	response.WriteHeader(statusCode)
+}
+
+var defaultPanicPage = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.StatusCode}} {{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Detail}}<p>{{.Detail}}</p>
+{{end}}{{if .CorrelationID}}<p>Correlation ID: <code>{{.CorrelationID}}</code></p>
+{{end}}</body>
+</html>
+`))
+
+var defaultPanicResponder = NegotiatePanicResponse(PlainTextPanicResponder{}, ProblemJSONPanicResponder{}, HTMLPanicResponder{})
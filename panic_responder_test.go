@@ -0,0 +1,127 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestPanicResponderFixture(t *testing.T) {
+	gunit.Run(new(PanicResponderFixture), t)
+}
+
+type PanicResponderFixture struct {
+	*gunit.Fixture
+
+	details PanicDetails
+}
+
+func (this *PanicResponderFixture) Setup() {
+	this.details = PanicDetails{StatusCode: 500, Title: "Internal Server Error", CorrelationID: "abc123"}
+}
+func (this *PanicResponderFixture) respond(responder PanicResponder, accept ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/", nil)
+	for _, value := range accept {
+		request.Header.Add("Accept", value)
+	}
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Length", "1234")
+	responder.RespondToPanic(recorder, request, this.details)
+	return recorder
+}
+
+func (this *PanicResponderFixture) TestPlainText() {
+	recorder := this.respond(PlainTextPanicResponder{})
+
+	this.So(recorder.Code, should.Equal, 500)
+	this.So(recorder.Header().Get("Content-Type"), should.Equal, "text/plain; charset=utf-8")
+	this.So(recorder.Header().Get("X-Content-Type-Options"), should.Equal, "nosniff")
+	this.So(recorder.Header().Get("Content-Length"), should.BeEmpty)
+	this.So(recorder.Body.String(), should.Equal, "Internal Server Error\nCorrelation ID: abc123\n")
+}
+func (this *PanicResponderFixture) TestProblemJSON() {
+	this.details.Detail = "public detail"
+
+	recorder := this.respond(ProblemJSONPanicResponder{Type: "https://example.com/problems/internal"})
+
+	var problem map[string]any
+	_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+	this.So(recorder.Header().Get("Content-Type"), should.Equal, "application/problem+json")
+	this.So(problem, should.Equal, map[string]any{
+		"type":           "https://example.com/problems/internal",
+		"title":          "Internal Server Error",
+		"status":         500.0,
+		"detail":         "public detail",
+		"correlation_id": "abc123",
+	})
+}
+func (this *PanicResponderFixture) TestHTML_DefaultPageEscapesDetails() {
+	this.details.Detail = "<script>"
+
+	recorder := this.respond(HTMLPanicResponder{})
+
+	this.So(recorder.Header().Get("Content-Type"), should.Equal, "text/html; charset=utf-8")
+	this.So(recorder.Body.String(), should.ContainSubstring, "<h1>Internal Server Error</h1>")
+	this.So(recorder.Body.String(), should.ContainSubstring, "&lt;script&gt;")
+	this.So(recorder.Body.String(), should.ContainSubstring, "<code>abc123</code>")
+}
+func (this *PanicResponderFixture) TestHTML_CustomTemplate() {
+	page := template.Must(template.New("").Parse("Oops {{.CorrelationID}}"))
+
+	recorder := this.respond(HTMLPanicResponder{Template: page})
+
+	this.So(recorder.Body.String(), should.Equal, "Oops abc123")
+}
+func (this *PanicResponderFixture) TestNegotiation() {
+	for accept, expected := range map[string]string{
+		"":                 "text/plain; charset=utf-8",
+		"*/*":              "text/plain; charset=utf-8",
+		"image/png":        "text/plain; charset=utf-8",
+		"application/json": "application/problem+json",
+		"application/*":    "application/problem+json",
+		"text/html,application/xhtml+xml,*/*;q=0.8": "text/html; charset=utf-8",
+		"text/plain;q=0.5, text/html":               "text/html; charset=utf-8",
+		"text/*;q=0.9, text/html;q=0.1":             "text/plain; charset=utf-8",
+		"application/json;q=0, text/html":           "text/html; charset=utf-8",
+	} {
+		recorder := this.respond(defaultPanicResponder, accept)
+		this.So(recorder.Header().Get("Content-Type"), should.Equal, expected)
+		this.So(recorder.Header().Get("Vary"), should.Equal, "Accept")
+	}
+}
+func (this *PanicResponderFixture) TestRecoveryHandler_UsesConfiguredResponderWithLoggedCorrelationID() {
+	var logged string
+	var config configuration
+	Options.apply(
+		Options.PanicResponder(ProblemJSONPanicResponder{}),
+		Options.Logger(printfFunc(func(format string, args ...any) { logged = fmt.Sprintf(format, args...) })),
+		Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boink") })),
+	)(&config)
+	recorder := httptest.NewRecorder()
+
+	config.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	var problem map[string]any
+	_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+	this.So(problem["correlation_id"], should.HaveLength, 32)
+	this.So(logged, should.ContainSubstring, fmt.Sprintf("[correlation ID: %s]", problem["correlation_id"]))
+}
+func (this *PanicResponderFixture) TestRecoveryHandler_NilResponderFallsBackToDefault() {
+	var config configuration
+	Options.apply(
+		Options.PanicResponder(nil),
+		Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boink") })),
+	)(&config)
+	recorder := httptest.NewRecorder()
+
+	config.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	this.So(recorder.Code, should.Equal, http.StatusInternalServerError)
+	this.So(recorder.Header().Get("Content-Type"), should.Equal, PlainTextPanicResponder{}.MediaType())
+}
@@ -0,0 +1,191 @@
+package httpserver
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PanicStormBehavior determines what happens once the PanicStormThreshold is reached.
+type PanicStormBehavior uint8
+
+const (
+	// PanicStormUnhealthy reports the server as unhealthy to the PanicStormHealth callback until the rate of panics
+	// falls below the threshold.
+	PanicStormUnhealthy PanicStormBehavior = iota
+
+	// PanicStormShutdown additionally shuts the server down gracefully, e.g. such that an orchestrator replaces it.
+	PanicStormShutdown
+)
+
+func (this PanicStormBehavior) String() string {
+	switch this {
+	case PanicStormUnhealthy:
+		return "unhealthy"
+	case PanicStormShutdown:
+		return "shutdown"
+	default:
+		return "unknown"
+	}
+}
+
+// panicStorm tracks the rate of recovered panics against the PanicStormThreshold and deduplicates panics having the same
+// stack trace within the PanicDeduplication interval, such that only the first occurrence is logged in full and the
+// number of duplicates is summarized once the interval concludes.
+type panicStorm struct {
+	logger      logger
+	dedupWindow time.Duration
+	threshold   int
+	interval    time.Duration
+	behavior    PanicStormBehavior
+	health      func(bool)
+	shutdown    func()
+	after       func(time.Duration, func())
+
+	mutex       sync.Mutex
+	occurrences map[string]*panicOccurrence
+	windowCount int
+	rolling     bool
+	active      bool
+}
+type panicOccurrence struct {
+	recovered  any
+	location   string
+	suppressed int
+}
+
+func newPanicStorm(config configuration) *panicStorm {
+	return &panicStorm{
+		logger:      config.Logger,
+		dedupWindow: config.PanicDeduplication,
+		threshold:   config.PanicStormThreshold,
+		interval:    config.PanicStormInterval,
+		behavior:    config.PanicStormBehavior,
+		health:      config.PanicStormHealth,
+		shutdown:    config.SoftShutdown,
+		after:       func(delay time.Duration, callback func()) { time.AfterFunc(delay, callback) },
+		occurrences: make(map[string]*panicOccurrence),
+	}
+}
+
+// record counts the panic reported and indicates whether it should be logged in full, i.e. it's not a duplicate.
+func (this *panicStorm) record(report PanicReport) bool {
+	this.mutex.Lock()
+	began := this.count()
+	first := this.deduplicate(report)
+	count := this.windowCount
+	this.mutex.Unlock()
+
+	if began {
+		this.begin(count)
+	}
+	return first
+}
+func (this *panicStorm) count() (began bool) {
+	if this.threshold <= 0 {
+		return false
+	}
+
+	if this.windowCount++; !this.rolling {
+		this.rolling = true
+		this.after(this.interval, this.rollover)
+	}
+
+	if this.windowCount >= this.threshold && !this.active {
+		this.active = true
+		return true
+	}
+	return false
+}
+func (this *panicStorm) begin(count int) {
+	action := "reporting unhealthy"
+	if this.behavior == PanicStormShutdown {
+		action = "shutting down"
+	}
+
+	attrs := []slog.Attr{slog.Int("panics", count), slog.Duration("interval", this.interval), slog.String("behavior", this.behavior.String())}
+	logEvent(this.logger, slog.LevelError, attrs, "Panic storm detected: %d panics within %s (threshold %d), %s.", count, this.interval, this.threshold, action)
+
+	if this.health != nil {
+		this.health(false)
+	}
+	if this.behavior == PanicStormShutdown && this.shutdown != nil {
+		this.shutdown()
+	}
+}
+func (this *panicStorm) rollover() {
+	this.mutex.Lock()
+	count := this.windowCount
+	wasActive := this.active
+	this.windowCount = 0
+	this.active = wasActive && count >= this.threshold
+	if this.rolling = this.active; this.rolling {
+		this.after(this.interval, this.rollover) // continues to summarize until the storm subsides
+	}
+	this.mutex.Unlock()
+
+	attrs := []slog.Attr{slog.Int("panics", count), slog.Duration("interval", this.interval)}
+	if !wasActive {
+		return
+	} else if count >= this.threshold {
+		logEvent(this.logger, slog.LevelError, attrs, "Panic storm ongoing: %d panics in the last %s (threshold %d).", count, this.interval, this.threshold)
+	} else {
+		logEvent(this.logger, slog.LevelInfo, attrs, "Panic storm subsided: %d panics in the last %s (threshold %d).", count, this.interval, this.threshold)
+		if this.health != nil {
+			this.health(true)
+		}
+	}
+}
+
+func (this *panicStorm) deduplicate(report PanicReport) bool {
+	if this.dedupWindow <= 0 {
+		return true
+	}
+
+	if len(this.occurrences) == 0 {
+		this.after(this.dedupWindow, this.summarize)
+	}
+
+	var key strings.Builder // identical stack traces, unlike the fingerprint, also have identical line numbers
+	for _, frame := range report.Frames {
+		_, _ = fmt.Fprintf(&key, "%s:%d;", frame.Function, frame.Line)
+	}
+
+	if occurrence, found := this.occurrences[key.String()]; found {
+		occurrence.suppressed++
+		return false
+	}
+
+	this.occurrences[key.String()] = &panicOccurrence{recovered: report.Value, location: report.location()}
+	return true
+}
+func (this *panicStorm) summarize() {
+	this.mutex.Lock()
+	occurrences := this.occurrences
+	this.occurrences = make(map[string]*panicOccurrence)
+	this.mutex.Unlock()
+
+	var duplicated []*panicOccurrence
+	total := 0
+	for _, occurrence := range occurrences {
+		if occurrence.suppressed > 0 {
+			duplicated = append(duplicated, occurrence)
+			total += occurrence.suppressed
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	slices.SortFunc(duplicated, func(a, b *panicOccurrence) int { return b.suppressed - a.suppressed })
+	var builder strings.Builder
+	for _, occurrence := range duplicated {
+		_, _ = fmt.Fprintf(&builder, "\n\t%d x %v [%s]", occurrence.suppressed, occurrence.recovered, occurrence.location)
+	}
+
+	attrs := []slog.Attr{slog.Int("suppressed", total), slog.Duration("interval", this.dedupWindow)}
+	logEvent(this.logger, slog.LevelWarn, attrs, "%d duplicate panic(s) suppressed in the last %s:%s", total, this.dedupWindow, builder.String())
+}
@@ -0,0 +1,180 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestPanicStormFixture(t *testing.T) {
+	gunit.Run(new(PanicStormFixture), t)
+}
+
+type PanicStormFixture struct {
+	*gunit.Fixture
+
+	handler *recoveryHandler
+
+	mutex     sync.Mutex
+	logged    []string
+	health    []bool
+	shutdown  int
+	scheduled []func()
+}
+
+func (this *PanicStormFixture) build(options ...option) {
+	var config configuration
+	Options.apply(append(options, Options.Logger(this), Options.PanicStormHealth(this.healthChanged))...)(&config)
+	this.handler = config.RecoveryHandler
+	this.handler.Handler = this
+	this.handler.storm.shutdown = func() { this.mutex.Lock(); this.shutdown++; this.mutex.Unlock() }
+	this.handler.storm.after = this.schedule
+}
+func (this *PanicStormFixture) serve(path string) {
+	this.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+}
+
+func (this *PanicStormFixture) TestDuplicateStackTraces_LoggedOnceThenSummarized() {
+	this.build(Options.PanicDeduplication(time.Millisecond*50), Options.PanicStormThreshold(1000, time.Minute))
+
+	for _, path := range []string{"/a", "/a", "/b", "/a"} {
+		this.serve(path) // identical stack traces require the same caller
+	}
+
+	if this.So(this.messages(), should.HaveLength, 2) {
+		this.So(this.messages()[0], should.StartWith, "[ERROR] Recovered panic: a")
+		this.So(this.messages()[1], should.StartWith, "[ERROR] Recovered panic: b")
+	}
+
+	this.elapse()
+	if this.So(this.messages(), should.HaveLength, 3) {
+		this.So(this.messages()[2], should.StartWith, "[WARN] 2 duplicate panic(s) suppressed in the last 50ms:\n\t2 x a [github.com/smarty/httpserver/v2.(*PanicStormFixture).ServeHTTP ")
+	}
+
+	this.serve("/a") // a new interval logs the first occurrence in full again
+	this.So(this.messages(), should.HaveLength, 4)
+}
+func (this *PanicStormFixture) TestDeduplicationDisabled_EveryPanicLogged() {
+	this.build()
+
+	for range 2 {
+		this.serve("/a")
+	}
+
+	this.So(this.messages(), should.HaveLength, 2)
+}
+func (this *PanicStormFixture) TestThresholdReached_UnhealthyUntilRateSubsides() {
+	this.build(Options.PanicStormThreshold(3, time.Millisecond*100))
+
+	this.serve("/a")
+	this.serve("/b")
+	this.So(this.healthChanges(), should.BeEmpty)
+
+	this.serve("/a")
+	this.So(this.healthChanges(), should.Equal, []bool{false})
+	this.So(this.messages(), should.Contain, "[ERROR] Panic storm detected: 3 panics within 100ms (threshold 3), reporting unhealthy.")
+
+	this.serve("/a")
+	this.elapse()
+	this.So(this.healthChanges(), should.Equal, []bool{false}) // still exceeded the threshold during the first interval
+
+	this.elapse()
+	this.So(this.healthChanges(), should.Equal, []bool{false, true})
+	this.So(this.messages(), should.Contain, "[INFO] Panic storm subsided: 0 panics in the last 100ms (threshold 3).")
+	this.So(this.shutdownCount(), should.Equal, 0)
+}
+func (this *PanicStormFixture) TestThresholdReachedWithShutdownBehavior_ServerShutdown() {
+	this.build(Options.PanicStormThreshold(2, time.Minute), Options.PanicStormBehavior(PanicStormShutdown))
+
+	this.serve("/a")
+	this.serve("/a")
+	this.serve("/a")
+
+	this.So(this.shutdownCount(), should.Equal, 1)
+	this.So(this.healthChanges(), should.Equal, []bool{false})
+	this.So(this.messages(), should.Contain, "[ERROR] Panic storm detected: 2 panics within 1m0s (threshold 2), shutting down.")
+}
+func (this *PanicStormFixture) TestThresholdReachedWithShutdownBehavior_GracefulShutdownBegins() {
+	var config configuration
+	Options.apply(Options.Handler(this), Options.Logger(this), Options.PanicStormThreshold(1, time.Minute), Options.PanicStormBehavior(PanicStormShutdown))(&config)
+
+	config.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+
+	this.So(config.SoftContext.Err(), should.Equal, context.Canceled)
+	this.So(config.Context.Err(), should.BeNil)
+}
+func (this *PanicStormFixture) TestStatusErrorsAndIgnoredErrors_NotCounted() {
+	this.build(Options.PanicStormThreshold(1, time.Minute))
+
+	this.serve("/status")
+	this.serve("/ignored")
+
+	this.So(this.healthChanges(), should.BeEmpty)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (this *PanicStormFixture) ServeHTTP(_ http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/status":
+		panic(StatusError{StatusCode: http.StatusBadRequest})
+	case "/b":
+		panic("b") // a distinct stack trace
+	case "/ignored":
+		panic(fmt.Errorf("inner: %w", context.Canceled))
+	default:
+		panic(strings.TrimPrefix(request.URL.Path, "/"))
+	}
+}
+func (this *PanicStormFixture) Printf(format string, args ...any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	message := fmt.Sprintf(format, args...)
+	if strings.Contains(message, "_test.go:") {
+		message, _, _ = strings.Cut(message, " [correlation ID")
+	}
+	this.logged = append(this.logged, message)
+}
+func (this *PanicStormFixture) schedule(_ time.Duration, callback func()) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.scheduled = append(this.scheduled, callback)
+}
+func (this *PanicStormFixture) elapse() {
+	this.mutex.Lock()
+	scheduled := this.scheduled
+	this.scheduled = nil
+	this.mutex.Unlock()
+
+	for _, callback := range scheduled {
+		callback()
+	}
+}
+func (this *PanicStormFixture) healthChanged(healthy bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.health = append(this.health, healthy)
+}
+func (this *PanicStormFixture) messages() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string(nil), this.logged...)
+}
+func (this *PanicStormFixture) healthChanges() []bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]bool(nil), this.health...)
+}
+func (this *PanicStormFixture) shutdownCount() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.shutdown
+}
@@ -1,50 +1,166 @@
 package httpserver
 
 import (
-	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
 	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"unicode"
 )
 
 type recoveryHandler struct {
 	http.Handler
+	settings atomic.Pointer[recoverySettings]
+	storm    *panicStorm
+	monitor  monitor
+	logger   logger
+	context  context.Context // cancelled once the server shuts down
+	tasks    sync.WaitGroup
+	running  atomic.Int32
+}
+type recoverySettings struct {
 	ignoredErrors  []error
 	dumpRawRequest bool
-	monitor        monitor
-	logger         logger
+	captureBody    bool
+	redaction      requestRedaction
+	responder      PanicResponder
+	classifier     PanicClassifier
+}
+
+func newRecoveryHandler(handler http.Handler, config configuration) *recoveryHandler {
+	this := &recoveryHandler{Handler: handler, storm: newPanicStorm(config), monitor: config.Monitor, logger: config.Logger, context: config.Context}
+	this.reload(config)
+	return this
 }
 
-func newRecoveryHandler(handler http.Handler, ignoredErrors []error, dumpRawRequest bool, monitor monitor, logger logger) http.Handler {
-	return &recoveryHandler{Handler: handler, ignoredErrors: ignoredErrors, dumpRawRequest: dumpRawRequest, monitor: monitor, logger: logger}
+func (this *recoveryHandler) reload(config configuration) {
+	if config.PanicResponder == nil {
+		config.PanicResponder = defaultPanicResponder
+	}
+
+	this.settings.Store(&recoverySettings{
+		ignoredErrors:  config.IgnoredErrors,
+		dumpRawRequest: config.DumpRequestOnPanic,
+		captureBody:    config.CaptureRequestBody,
+		redaction:      newRequestRedaction(config),
+		responder:      config.PanicResponder,
+		classifier:     config.PanicClassifier,
+	})
 }
 
 func (this *recoveryHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	defer this.finally(response, request)
-	this.Handler.ServeHTTP(response, request)
+	tracked := newTrackingResponseWriter(response)
+	if settings := this.settings.Load(); settings.dumpRawRequest && settings.captureBody && request.Body != nil && request.Body != http.NoBody {
+		request.Body = newCapturedBody(request.Body, cmp.Or(settings.redaction.maxBodySize, maxCapturedBodySize))
+	}
+
+	defer this.finally(tracked, request)
+	this.Handler.ServeHTTP(tracked.exposed(), request.WithContext(context.WithValue(request.Context(), recoveryKey{}, this))) // see Go
 }
 
-func (this *recoveryHandler) finally(response http.ResponseWriter, request *http.Request) {
+func (this *recoveryHandler) finally(response *trackingResponseWriter, request *http.Request) {
 	err := recover()
 	if err == nil {
 		return
 	}
 
-	this.logRecovery(err, request)
-	this.internalServerError(response)
+	if recovered, ok := err.(error); ok && errors.Is(recovered, http.ErrAbortHandler) {
+		panic(http.ErrAbortHandler) // net/http aborts the response silently
+	}
+
+	// Once the status has been sent, writing an error response would only append to (and corrupt) the response the
+	// client has started receiving.
+	committed := response.status != 0 || response.hijacked
+
+	correlationID := newCorrelationID(request)
+	classification := this.classify(err, request)
+	if classification.Severity == PanicSeverityError {
+		this.recordSpanError(err, request)
+	}
+	message := "Recovered panic"
+	if committed {
+		message = "Recovered panic after the response was committed (aborting response)"
+	}
+	this.logRecovery(err, request, correlationID, message, committed, classification)
+
+	if !committed {
+		this.respond(response, request, classification.StatusCode, classification.Detail, correlationID)
+	} else if !response.hijacked {
+		panic(http.ErrAbortHandler) // closes the connection (HTTP/1) or resets the stream (HTTP/2) without logging
+	}
+}
+
+func (this *recoveryHandler) logRecovery(recovered any, request *http.Request, correlationID, message string, committed bool, classification PanicClassification) {
+	if classification.Severity == PanicSeverityIgnored {
+		if monitor, ok := this.monitor.(ignoredPanicMonitor); ok {
+			monitor.PanicIgnored(request, recovered)
+		}
+		return
+	}
+
+	report := newPanicReport(recovered, request, correlationID, committed, this.settings.Load().redaction)
+	if monitor, ok := this.monitor.(panicReportMonitor); ok {
+		monitor.PanicReported(report)
+	}
+
+	if monitor, ok := this.monitor.(abortedPanicMonitor); ok && committed {
+		monitor.PanicAborted(request, recovered)
+	} else {
+		this.monitor.PanicRecovered(request, recovered)
+	}
+
+	if classification.Severity != PanicSeverityError {
+		level := slog.LevelWarn
+		if classification.Severity == PanicSeverityInfo {
+			level = slog.LevelInfo
+		}
+		attrs := append(requestAttrs(request), slog.Int("status", classification.StatusCode), slog.String("correlation_id", correlationID))
+		logEvent(this.logger, level, attrs, "%s: %v [correlation ID: %s]", message, recovered, correlationID)
+		return
+	}
+
+	if !this.storm.record(report) {
+		return // a duplicate of a panic already logged in full, which is summarized later
+	}
+
+	if structured, ok := this.logger.(*slogLogger); ok {
+		attrs := append(requestAttrs(request), slog.Any("panic", recovered), slog.String("correlation_id", correlationID), slog.String("fingerprint", report.Fingerprint), slog.Bool("aborted", committed), slog.String("stack", report.stack()))
+		if dump := this.requestToString(request); len(dump) > 0 {
+			attrs = append(attrs, slog.String("request", dump))
+		}
+		structured.log(slog.LevelError, fmt.Sprintf("%s: %v", message, recovered), attrs...)
+	} else {
+		this.logger.Printf("[ERROR] %s: %v [correlation ID: %s]\n%s%s", message, recovered, correlationID, report.stack(), this.requestToString(request))
+	}
+}
+
+func asStatusError(recovered any) (StatusError, bool) {
+	var statusError StatusError
+	err, isErr := recovered.(error)
+	return statusError, isErr && errors.As(err, &statusError)
 }
 
-func (this *recoveryHandler) logRecovery(recovered any, request *http.Request) {
-	if this.isIgnoredError(recovered) {
+func (this *recoveryHandler) recordSpanError(recovered any, request *http.Request) {
+	span := SpanFromContext(request.Context())
+	if span == nil {
 		return
 	}
 
-	this.monitor.PanicRecovered(request, recovered)
-	this.logger.Printf("[ERROR] Recovered panic: %v\n%s%s", recovered, debug.Stack(), this.requestToString(request))
+	message := fmt.Sprint(recovered)
+	span.AddEvent("exception", map[string]any{
+		"exception.type":       fmt.Sprintf("%T", recovered),
+		"exception.message":    message,
+		"exception.stacktrace": string(debug.Stack()),
+	})
+	span.SetError("panic: " + message)
 }
 
 func (this *recoveryHandler) isIgnoredError(recovered any) bool {
@@ -53,7 +169,7 @@ func (this *recoveryHandler) isIgnoredError(recovered any) bool {
 		return false
 	}
 
-	for _, ignored := range this.ignoredErrors {
+	for _, ignored := range this.settings.Load().ignoredErrors {
 		if errors.Is(err, ignored) {
 			return true
 		}
@@ -62,30 +178,47 @@ func (this *recoveryHandler) isIgnoredError(recovered any) bool {
 	return false
 }
 
-func (this *recoveryHandler) internalServerError(response http.ResponseWriter) {
-	http.Error(response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+func (this *recoveryHandler) respond(response http.ResponseWriter, request *http.Request, status int, detail, correlationID string) {
+	this.settings.Load().responder.RespondToPanic(response, request, PanicDetails{
+		StatusCode:    status,
+		Title:         http.StatusText(status),
+		Detail:        detail,
+		CorrelationID: correlationID,
+	})
+}
+
+// newCorrelationID identifies a recovered panic using the ID of the request (see Options.RequestID), or the trace ID of
+// the request, if traced, otherwise a random ID.
+func newCorrelationID(request *http.Request) string {
+	if id := RequestIDFromContext(request.Context()); len(id) > 0 {
+		return id
+	}
+	if span := SpanFromContext(request.Context()); span != nil {
+		return span.TraceID.String()
+	}
+
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
 }
 
 func (this *recoveryHandler) requestToString(request *http.Request) string {
-	if !this.dumpRawRequest {
+	settings := this.settings.Load()
+	if !settings.dumpRawRequest {
 		return ""
 	}
 
-	raw, err := httputil.DumpRequest(request, true)
+	raw, err := settings.redaction.dump(request)
 	formatted := strings.Map(func(r rune) rune {
 		if r == '\n' || r == '\t' || unicode.IsPrint(r) {
 			return r
 		}
 		return '?'
-	}, strings.ReplaceAll(string(raw), "\r\n", "\n\t"))
+	}, strings.ReplaceAll(raw, "\r\n", "\n\t"))
 
 	if err != nil {
-		// manually write out request info as it will not be returned on error (e.g. when Body is closed)
-		// [see implementation of DumpRequest]
-		b := new(bytes.Buffer)
-		_, _ = fmt.Fprintf(b, "%s %s HTTP/%d.%d\r\n", request.Method, request.RequestURI, request.ProtoMajor, request.ProtoMinor)
-		_ = request.Header.WriteSubset(b, nil)
-		formatted = fmt.Sprintf("%s [request formatting error: %s]", b.String(), err)
+		// only the request line and headers are written when the body is unreadable (e.g. when Body is closed)
+		formatted = fmt.Sprintf("%s [request formatting error: %s]", raw, err)
 	}
 
 	return fmt.Sprint("Recovered request: ", formatted)
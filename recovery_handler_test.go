@@ -31,6 +31,7 @@ type RecoveryHandlerFixture struct {
 	serveHTTPResponse http.ResponseWriter
 	serveHTTPRequest  *http.Request
 	serveHTTPError    any
+	serveHTTPWritten  string
 
 	panicRecoveredCount   int
 	panicRecoveredRequest *http.Request
@@ -42,16 +43,23 @@ type RecoveryHandlerFixture struct {
 func (this *RecoveryHandlerFixture) Setup() {
 	this.response = httptest.NewRecorder()
 	this.request = httptest.NewRequest("GET", "/", nil)
-	ignoredErrors := []error{context.Canceled, context.DeadlineExceeded, sql.ErrTxDone}
-	this.handler = newRecoveryHandler(this, ignoredErrors, true, this, this)
+	var config configuration
+	Options.apply(
+		Options.IgnoredErrors(context.Canceled, context.DeadlineExceeded, sql.ErrTxDone),
+		Options.DumpRequestOnPanic(true),
+		Options.Monitor(this),
+		Options.Logger(this),
+	)(&config)
+	this.handler = newRecoveryHandler(this, config)
 }
 
 func (this *RecoveryHandlerFixture) TestInnerHandlerCalled() {
 	this.handler.ServeHTTP(this.response, this.request)
 
 	this.So(this.serveHTTPCount, should.Equal, 1)
-	this.So(this.serveHTTPResponse, should.Equal, this.response)
-	this.So(this.serveHTTPRequest, should.Equal, this.request)
+	this.So(this.serveHTTPResponse.(interface{ Unwrap() http.ResponseWriter }).Unwrap(), should.Equal, this.response)
+	this.So(this.serveHTTPRequest.WithContext(this.request.Context()), should.Equal, this.request) // only the context differs
+	this.So(this.serveHTTPRequest.Context().Value(recoveryKey{}), should.Equal, this.handler)
 }
 func (this *RecoveryHandlerFixture) TestInnerHandlerDoesNotPanic_NotRecoveryNecessary() {
 	this.handler.ServeHTTP(this.response, this.request)
@@ -128,6 +136,53 @@ func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_PostData_ReturnHTTP500
 		this.So(this.logged[0], should.EndWith, "=value2?")
 	}
 }
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_CapturedPostData_DumpIncludesConsumedBody() {
+	var config configuration
+	Options.apply(Options.DumpRequestOnPanic(true), Options.CaptureRequestBody(true), Options.Monitor(this), Options.Logger(this))(&config)
+	this.handler = newRecoveryHandler(this, config)
+	body := bytes.NewReader([]byte("field1=value1&field2=value2\000"))
+	this.request = httptest.NewRequest("POST", "/", body)
+
+	this.serveHTTPError = "panic value"
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 500)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.EndWith, "\n\tfield1=value1&field2=value2?")
+	}
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_CapturedPostDataExceedingLimit_DumpTruncated() {
+	var config configuration
+	Options.apply(Options.DumpRequestOnPanic(true), Options.CaptureRequestBody(true), Options.DumpMaxBodySize(8), Options.Logger(this))(&config)
+	this.handler = newRecoveryHandler(this, config)
+	this.request = httptest.NewRequest("POST", "/", bytes.NewReader([]byte("field1=value1&field2=value2")))
+
+	this.serveHTTPError = "panic value"
+	this.handler.ServeHTTP(this.response, this.request)
+
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.EndWith, "\n\tfield1=v... [body truncated to 8 bytes]")
+	}
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_CapturedPostDataClosedByHandler_DumpIncludesConsumedBody() {
+	var config configuration
+	Options.apply(Options.DumpRequestOnPanic(true), Options.CaptureRequestBody(true), Options.Logger(this),
+		Options.Handler(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			_, _ = io.ReadAll(request.Body)
+			_ = request.Body.Close()
+			panic("panic value")
+		})))(&config)
+	server := httptest.NewServer(config.Handler)
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "text/plain", bytes.NewReader([]byte("field1=value1&field2=value2")))
+
+	this.So(err, should.BeNil)
+	this.So(response.StatusCode, should.Equal, 500)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.EndWith, "\n\tfield1=value1&field2=value2")
+	}
+}
 
 func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_PostDataClosed_ReturnHTTP500() {
 	this.request = httptest.NewRequest("HEAD", "/", dummyReader{})
@@ -144,6 +199,94 @@ func (this *RecoveryHandlerFixture) TestInnerHandlerPanic_PostDataClosed_ReturnH
 		this.So(this.logged[0], should.ContainSubstring, "HEAD /")
 	}
 }
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicAfterWriting_ResponseAborted() {
+	this.serveHTTPWritten = "partial"
+	this.serveHTTPError = "panic value"
+
+	defer func() {
+		this.So(recover(), should.Equal, http.ErrAbortHandler)
+		this.So(this.response.Code, should.Equal, 200)
+		this.So(this.response.Body.String(), should.Equal, "partial")
+		this.So(this.panicRecoveredCount, should.Equal, 1)
+		if this.So(this.logged, should.HaveLength, 1) {
+			this.So(this.logged[0], should.StartWith, "[ERROR] Recovered panic after the response was committed (aborting response): panic value")
+		}
+	}()
+
+	this.handler.ServeHTTP(this.response, this.request)
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicsWithErrAbortHandler_AbortedSilently() {
+	this.serveHTTPError = fmt.Errorf("inner: %w", http.ErrAbortHandler)
+
+	defer func() {
+		this.So(recover(), should.Equal, http.ErrAbortHandler)
+		this.So(this.response.Body.Len(), should.Equal, 0)
+		this.So(this.panicRecoveredCount, should.Equal, 0)
+		this.So(this.logged, should.BeEmpty)
+	}()
+
+	this.handler.ServeHTTP(this.response, this.request)
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicsWithStatusError_RespondWithStatusAndLogWithoutStack() {
+	this.serveHTTPError = fmt.Errorf("lookup: %w", StatusError{StatusCode: 404, Message: "no such widget", Err: sql.ErrNoRows})
+
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 404)
+	this.So(this.response.Body.String(), should.StartWith, "Not Found\nno such widget\nCorrelation ID: ")
+	this.So(this.panicRecoveredCount, should.Equal, 1)
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.StartWith, "[INFO] Recovered panic: lookup: 404 Not Found: no such widget: sql: no rows in result set [correlation ID: ")
+		this.So(this.logged[0], should.NotContainSubstring, "_test.go:")
+	}
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicsWithServerStatusError_LoggedAsWarning() {
+	this.serveHTTPError = StatusError{StatusCode: 503}
+
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 503)
+	this.So(this.response.Body.String(), should.StartWith, "Service Unavailable\nCorrelation ID: ")
+	if this.So(this.logged, should.HaveLength, 1) {
+		this.So(this.logged[0], should.StartWith, "[WARN] Recovered panic: 503 Service Unavailable [correlation ID: ")
+	}
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicsWithNonErrorStatus_TreatedAsHTTP500() {
+	this.serveHTTPError = StatusError{StatusCode: 200, Message: "fine"}
+
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 500)
+	this.So(this.response.Body.String(), should.StartWith, "Internal Server Error\nfine\n")
+}
+func (this *RecoveryHandlerFixture) TestInnerHandlerPanicAfterWriting_ClientObservesFailureAndMonitorNotified() {
+	metrics := NewMetrics()
+	var config configuration
+	Options.apply(
+		Options.Monitor(metrics),
+		Options.Logger(this),
+		Options.Handler(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.Header().Set("Content-Length", "100")
+			_, _ = io.WriteString(response, "partial")
+			http.NewResponseController(response).Flush()
+			panic("panic value")
+		})),
+	)(&config)
+	server := httptest.NewServer(config.Handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if this.So(err, should.BeNil) {
+		_, err = io.ReadAll(response.Body)
+		_ = response.Body.Close()
+	}
+
+	this.So(err, should.NotBeNil)
+	output := new(bytes.Buffer)
+	_, _ = metrics.WriteTo(output)
+	this.So(output.String(), should.ContainSubstring, `http_server_panics_total{outcome="aborted"} 1`+"\n")
+	this.So(output.String(), should.ContainSubstring, `http_server_panics_total{outcome="recovered"} 0`+"\n")
+}
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
@@ -158,6 +301,9 @@ func (this *RecoveryHandlerFixture) ServeHTTP(response http.ResponseWriter, requ
 			_, _ = io.ReadAll(request.Body)
 			_ = request.Body.Close()
 		}
+		if len(this.serveHTTPWritten) > 0 {
+			_, _ = io.WriteString(response, this.serveHTTPWritten)
+		}
 		panic(this.serveHTTPError)
 	}
 }
@@ -0,0 +1,85 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Go runs the task provided in a new goroutine on behalf of the request being handled, such that a panic is recovered
+// in the same way as a panic of the handler (e.g. classified, reported to the monitor and logged along with a dump of
+// the request) rather than crashing the process. The context provided to the task carries the values of the request
+// context but, unlike the request context, isn't cancelled once the response has been written. It's cancelled once the
+// server shuts down, and shutting down waits for every task to complete as it would for requests in flight.
+//
+// The task runs without recovery unless the request is being handled by the recovery handler, e.g. when HandlePanic is
+// disabled.
+func Go(request *http.Request, task func(ctx context.Context)) {
+	recovery, _ := request.Context().Value(recoveryKey{}).(*recoveryHandler)
+	if recovery == nil {
+		go task(context.WithoutCancel(request.Context()))
+		return
+	}
+
+	recovery.tasks.Add(1)
+	recovery.running.Add(1)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(request.Context()))
+	stop := context.AfterFunc(recovery.context, cancel)
+	request = snapshotRequest(request, ctx)
+
+	go func() {
+		defer recovery.tasks.Done()
+		defer recovery.running.Add(-1)
+		defer stop()
+		defer cancel()
+		defer recovery.recoverTask(request)
+		task(ctx)
+	}()
+}
+
+func (this *recoveryHandler) recoverTask(request *http.Request) {
+	err := recover()
+	if err == nil {
+		return
+	}
+
+	if recovered, ok := err.(error); ok && errors.Is(recovered, http.ErrAbortHandler) {
+		return // there's no response to abort
+	}
+
+	this.logRecovery(err, request, newCorrelationID(request), "Recovered panic in goroutine", false, this.classify(err, request))
+}
+
+// snapshotRequest copies the request such that a panic of the task can be reported without touching the request still
+// being handled, in particular its body. Only the part of the body already captured (see CaptureRequestBody) is dumped.
+func snapshotRequest(request *http.Request, ctx context.Context) *http.Request {
+	snapshot := request.Clone(ctx)
+	snapshot.Body = http.NoBody
+	if captured, ok := request.Body.(*capturedBody); ok {
+		snapshot.Body = io.NopCloser(bytes.NewReader(bytes.Clone(captured.buffer)))
+	}
+	return snapshot
+}
+
+// awaitTasks waits until every task started using Go completes or the context provided is done.
+func (this *recoveryHandler) awaitTasks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		this.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if this.running.Load() == 0 {
+			return nil // the context was already done (e.g. the parent of the server context was cancelled)
+		}
+		return ctx.Err()
+	}
+}
+
+type recoveryKey struct{}
@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestRecoveryTaskFixture(t *testing.T) {
+	gunit.Run(new(RecoveryTaskFixture), t)
+}
+
+type RecoveryTaskFixture struct {
+	*gunit.Fixture
+
+	shutdown context.CancelFunc
+	handler  *recoveryHandler
+	served   http.Handler
+	request  *http.Request
+	task     func(context.Context)
+
+	mutex     sync.Mutex
+	recovered []any
+	logged    []string
+}
+
+func (this *RecoveryTaskFixture) Setup() {
+	var config configuration
+	Options.apply(
+		Options.Context(this.T().Context()),
+		Options.IgnoredErrors(context.Canceled),
+		Options.Handler(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) { Go(request, this.task) })),
+		Options.Monitor(this),
+		Options.Logger(this),
+	)(&config)
+	this.shutdown = config.ContextShutdown
+	this.handler = config.RecoveryHandler
+	this.served = config.Handler
+	this.request = httptest.NewRequest("GET", "/task", nil)
+}
+
+func (this *RecoveryTaskFixture) TestTaskPanics_RecoveredAndLoggedWithRequest() {
+	this.serve(func(context.Context) { panic("boom") })
+
+	this.So(this.handler.awaitTasks(this.T().Context()), should.BeNil)
+	this.So(this.recovered, should.Equal, []any{"boom"})
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.StartWith, "[ERROR] Recovered panic in goroutine: boom")
+}
+func (this *RecoveryTaskFixture) TestTaskPanicsWithIgnoredError_NotLogged() {
+	this.serve(func(context.Context) { panic(context.Canceled) })
+
+	this.So(this.handler.awaitTasks(this.T().Context()), should.BeNil)
+	this.So(this.recovered, should.BeEmpty)
+	this.So(this.logged, should.BeEmpty)
+}
+func (this *RecoveryTaskFixture) TestTaskPanicsWithRequestDump_BodyOfHandlerUntouched() {
+	var config configuration
+	var remainder []byte
+	Options.apply(
+		Options.DumpRequestOnPanic(true),
+		Options.CaptureRequestBody(true),
+		Options.Handler(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			_, _ = request.Body.Read(make([]byte, 7))
+			Go(request, func(context.Context) { panic("boom") })
+			_ = config.RecoveryHandler.awaitTasks(this.T().Context())
+			remainder, _ = io.ReadAll(request.Body)
+		})),
+		Options.Logger(this),
+	)(&config)
+
+	config.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("field1=value1&field2=value2")))
+
+	this.So(string(remainder), should.Equal, "value1&field2=value2")
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.EndWith, "\n\tfield1=")
+}
+func (this *RecoveryTaskFixture) TestTaskContext_RetainsRequestValuesButNotCancellation() {
+	ctx, cancel := context.WithCancel(context.WithValue(this.request.Context(), taskKey{}, "value"))
+	cancel() // e.g. once the response has been written
+	this.request = this.request.WithContext(ctx)
+	var value any
+	var err error
+
+	this.serve(func(ctx context.Context) { value, err = ctx.Value(taskKey{}), ctx.Err() })
+
+	this.So(this.handler.awaitTasks(this.T().Context()), should.BeNil)
+	this.So(value, should.Equal, "value")
+	this.So(err, should.BeNil)
+}
+func (this *RecoveryTaskFixture) TestServerShutdown_TaskContextCancelledAndAwaited() {
+	started := make(chan struct{})
+	completed := false
+	this.serve(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		completed = true
+	})
+	<-started
+
+	this.shutdown()
+
+	this.So(this.handler.awaitTasks(this.T().Context()), should.BeNil)
+	this.So(completed, should.BeTrue)
+}
+func (this *RecoveryTaskFixture) TestTaskOutstanding_AwaitTimesOut() {
+	release := make(chan struct{})
+	defer close(release)
+	this.serve(func(context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(this.T().Context(), time.Millisecond*10)
+	defer cancel()
+
+	this.So(errors.Is(this.handler.awaitTasks(ctx), context.DeadlineExceeded), should.BeTrue)
+}
+func (this *RecoveryTaskFixture) TestWithoutRecovery_TaskRunsUnrecovered() {
+	done := make(chan context.Context)
+
+	Go(httptest.NewRequest("GET", "/", nil), func(ctx context.Context) { done <- ctx })
+
+	this.So(<-done, should.NotBeNil)
+}
+
+func (this *RecoveryTaskFixture) serve(task func(context.Context)) {
+	this.task = task
+	this.served.ServeHTTP(httptest.NewRecorder(), this.request)
+}
+func (this *RecoveryTaskFixture) PanicRecovered(_ *http.Request, err any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.recovered = append(this.recovered, err)
+}
+func (this *RecoveryTaskFixture) Printf(format string, args ...any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.logged = append(this.logged, strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+type taskKey struct{}
@@ -0,0 +1,162 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+)
+
+// Reload applies the options provided to the running server. Only the following settings may be reloaded:
+//   - ReadRequestTimeout, ReadRequestHeaderTimeout, WriteResponseTimeout, IdleConnectionTimeout, MaxRequestHeaderSize
+//     (applied to new connections by handing the bound listener over to a new http.Server; existing connections are
+//     gracefully drained by the previous http.Server)
+//   - TLSConfig (applied to subsequent TLS handshakes; TLS can neither be enabled nor disabled)
+//   - IgnoredErrors, DumpRequestOnPanic, DumpRedactHeaders, DumpAllowHeaders, DumpRedactQuery, DumpRedactFields,
+//     DumpMaxBodySize, CaptureRequestBody, PanicResponder, PanicClassifier (applied to subsequent panics; DumpRedactQuery
+//     also to the spans of subsequent requests)
+//   - AccessLogFormat, AccessLogFields, AccessLogHeaders, AccessLogSampleRate, AccessLogSlowThreshold (applied to
+//     subsequent requests)
+//
+// Options for any other setting are rejected whenever they're provided, even when their value is unchanged, such that
+// options loaded for a reload (e.g. using LoadFile) must only contain the settings above. Nothing is applied unless
+// every setting is valid.
+func (this *defaultServer) Reload(options ...option) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.softContext.Err() != nil {
+		return ErrServerShutdown
+	}
+
+	updated := this.config
+	for _, item := range options {
+		item(&updated)
+	}
+
+	if err := this.validateReload(updated, optionNames(options...)); err != nil {
+		return err
+	}
+
+	if this.recoveryHandler != nil {
+		this.recoveryHandler.reload(updated)
+	}
+
+	if this.accessLogHandler != nil {
+		this.accessLogHandler.reload(updated)
+	}
+
+	if this.tracingHandler != nil {
+		this.tracingHandler.reload(updated)
+	}
+
+	if updated.TLSConfig != nil {
+		this.tlsConfig.Store(updated.TLSConfig)
+	}
+
+	if httpServerChanged(this.config, updated) {
+		previous := this.httpServer
+		this.httpServer = newHTTPServer(updated)
+		this.drains.Add(1)
+		go this.drain(previous)
+	}
+
+	this.config = updated
+	logEvent(this.logger, slog.LevelInfo, this.listenAttrs(), "HTTP server settings reloaded. [%s]", this.listenAddress)
+	return nil
+}
+func (this *defaultServer) validateReload(updated configuration, provided map[string]bool) error {
+	var errs []error
+
+	for _, name := range slices.Sorted(maps.Keys(provided)) {
+		if !reloadableOptions[name] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrSettingNotReloadable, name))
+		}
+	}
+
+	if (this.config.TLSConfig == nil) != (updated.TLSConfig == nil) {
+		errs = append(errs, fmt.Errorf("%w: TLSConfig cannot enable or disable TLS", ErrSettingNotReloadable))
+	}
+
+	if !this.reloadable && httpServerChanged(this.config, updated) {
+		errs = append(errs, fmt.Errorf("%w: timeouts and header size of a custom HTTPServer", ErrSettingNotReloadable))
+	}
+
+	errs = append(errs, updated.validateHTTPServer()...)
+	errs = append(errs, updated.validateAccessLog()...)
+	errs = append(errs, updated.validateRequestDump()...)
+
+	return errors.Join(errs...)
+}
+func httpServerChanged(previous, updated configuration) bool {
+	return previous.MaxRequestHeaderSize != updated.MaxRequestHeaderSize ||
+		previous.ReadRequestTimeout != updated.ReadRequestTimeout ||
+		previous.ReadRequestHeaderTimeout != updated.ReadRequestHeaderTimeout ||
+		previous.WriteResponseTimeout != updated.WriteResponseTimeout ||
+		previous.IdleConnectionTimeout != updated.IdleConnectionTimeout
+}
+
+func (this *defaultServer) drain(previous httpServer) {
+	defer this.drains.Done()
+	ctx, cancel := context.WithTimeout(this.hardContext, this.shutdownTimeout)
+	defer cancel()
+	_ = previous.Shutdown(ctx) // in-flight requests complete on the previous http.Server
+}
+
+func (this *defaultServer) watchReload(waiter *sync.WaitGroup) {
+	defer waiter.Done()
+
+	if this.reloadOptions == nil {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, this.reloadSignals...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-this.softContext.Done():
+			return
+		case received := <-signals:
+			if err := this.Reload(this.reloadOptions()...); err != nil {
+				logEvent(this.logger, slog.LevelWarn, this.listenAttrs(slog.String("signal", received.String()), slog.Any("error", err)),
+					"Unable to reload HTTP server settings on [%s]: [%s]", received, err)
+			}
+		}
+	}
+}
+
+var reloadableOptions = map[string]bool{
+	"ReadRequestTimeout":       true,
+	"ReadRequestHeaderTimeout": true,
+	"WriteResponseTimeout":     true,
+	"IdleConnectionTimeout":    true,
+	"MaxRequestHeaderSize":     true,
+	"TLSConfig":                true,
+	"IgnoredErrors":            true,
+	"DumpRequestOnPanic":       true,
+	"DumpRedactHeaders":        true,
+	"DumpAllowHeaders":         true,
+	"DumpRedactQuery":          true,
+	"DumpRedactFields":         true,
+	"DumpMaxBodySize":          true,
+	"CaptureRequestBody":       true,
+	"PanicResponder":           true,
+	"PanicClassifier":          true,
+	"AccessLogFormat":          true,
+	"AccessLogFields":          true,
+	"AccessLogHeaders":         true,
+	"AccessLogSampleRate":      true,
+	"AccessLogSlowThreshold":   true,
+}
+
+var (
+	ErrServerShutdown       = errors.New("the server is shutting down")
+	ErrSettingNotReloadable = errors.New("setting cannot be reloaded")
+)
@@ -0,0 +1,200 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestReloadFixture(t *testing.T) {
+	gunit.Run(new(ReloadFixture), t)
+}
+
+type ReloadFixture struct {
+	*gunit.Fixture
+
+	server  *defaultServer
+	address chan string
+
+	mutex  sync.Mutex
+	logged []string
+}
+
+func (this *ReloadFixture) Setup() {
+	this.address = make(chan string, 1)
+	this.server = New(
+		Options.ListenAddress("127.0.0.1:0"),
+		Options.ListenAdapter(func(listener net.Listener) net.Listener {
+			this.address <- listener.Addr().String()
+			return listener
+		}),
+		Options.Handler(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprint(response, "hello")
+		})),
+		Options.Logger(this),
+	).(*defaultServer)
+}
+
+func (this *ReloadFixture) TestReloadTimeouts_NewHTTPServerCreatedWithUpdatedSettings() {
+	previous := this.server.currentHTTPServer()
+
+	err := this.server.Reload(
+		Options.ReadRequestTimeout(time.Second*7),
+		Options.ReadRequestHeaderTimeout(time.Second*2),
+		Options.WriteResponseTimeout(time.Second*3),
+		Options.IdleConnectionTimeout(time.Second*4),
+		Options.MaxRequestHeaderSize(4096),
+	)
+
+	this.So(err, should.BeNil)
+	current := this.server.currentHTTPServer().(*http.Server)
+	this.So(current != previous, should.BeTrue)
+	this.So(current.ReadTimeout, should.Equal, time.Second*7)
+	this.So(current.ReadHeaderTimeout, should.Equal, time.Second*2)
+	this.So(current.WriteTimeout, should.Equal, time.Second*3)
+	this.So(current.IdleTimeout, should.Equal, time.Second*4)
+	this.So(current.MaxHeaderBytes, should.Equal, 4096)
+	this.So(current.Handler == previous.(*http.Server).Handler, should.BeTrue)
+}
+func (this *ReloadFixture) TestReloadPanicSettings_RecoveryHandlerUpdatedWithoutReplacingHTTPServer() {
+	previous := this.server.currentHTTPServer()
+
+	err := this.server.Reload(Options.DumpRequestOnPanic(true), Options.IgnoredErrors(io.EOF))
+
+	this.So(err, should.BeNil)
+	this.So(this.server.currentHTTPServer() == previous, should.BeTrue)
+	this.So(this.server.recoveryHandler.settings.Load().dumpRawRequest, should.BeTrue)
+	this.So(this.server.recoveryHandler.settings.Load().ignoredErrors, should.Equal, []error{io.EOF})
+}
+func (this *ReloadFixture) TestReloadInvalidSettings_NothingApplied() {
+	previous := this.server.currentHTTPServer()
+
+	err := this.server.Reload(
+		Options.DumpRequestOnPanic(true),
+		Options.ReadRequestTimeout(-time.Second),
+		Options.ListenAddress("127.0.0.1:8080"),
+		Options.TLSConfig(&tls.Config{}),
+	)
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(errors.Is(err, ErrSettingNotReloadable), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "ReadRequestTimeout")
+	this.So(err.Error(), should.ContainSubstring, "ListenAddress")
+	this.So(err.Error(), should.ContainSubstring, "TLSConfig")
+	this.So(this.server.currentHTTPServer() == previous, should.BeTrue)
+	this.So(this.server.recoveryHandler.settings.Load().dumpRawRequest, should.BeFalse)
+}
+func (this *ReloadFixture) TestReloadTimeoutsOfCustomHTTPServer_Rejected() {
+	this.server = New(Options.HTTPServer(&http.Server{})).(*defaultServer)
+
+	err := this.server.Reload(Options.IdleConnectionTimeout(time.Minute))
+
+	this.So(errors.Is(err, ErrSettingNotReloadable), should.BeTrue)
+}
+func (this *ReloadFixture) TestReloadListenerSettings_RejectedEvenWhenUnchanged() {
+	err := this.server.Reload(
+		Options.MaxConnections(100),
+		Options.ListenBacklog(this.server.config.ListenBacklog),
+		Options.DumpRequestOnPanic(true),
+	)
+
+	this.So(errors.Is(err, ErrSettingNotReloadable), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "MaxConnections")
+	this.So(err.Error(), should.ContainSubstring, "ListenBacklog")
+	this.So(this.server.recoveryHandler.settings.Load().dumpRawRequest, should.BeFalse)
+}
+func (this *ReloadFixture) TestReloadFunctionSettings_Rejected() {
+	err := this.server.Reload(
+		Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})),
+		Options.RequestIDGenerator(func() string { return "id" }),
+		Options.DumpRequestOnPanic(true),
+	)
+
+	this.So(errors.Is(err, ErrSettingNotReloadable), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "Handler")
+	this.So(err.Error(), should.ContainSubstring, "RequestIDGenerator")
+	this.So(this.server.recoveryHandler.settings.Load().dumpRawRequest, should.BeFalse)
+}
+func (this *ReloadFixture) TestReloadAfterClose_Rejected() {
+	_ = this.server.Close()
+
+	err := this.server.Reload(Options.DumpRequestOnPanic(true))
+
+	this.So(err, should.Equal, ErrServerShutdown)
+}
+func (this *ReloadFixture) TestReloadWhileListening_ListenerHandedToNewHTTPServer() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		this.server.Listen()
+	}()
+	address := <-this.address
+
+	before := this.get(address)
+	err := this.server.Reload(Options.IdleConnectionTimeout(time.Second * 10))
+	after := this.get(address)
+	_ = this.server.Close()
+	<-done
+
+	this.So(err, should.BeNil)
+	this.So(before, should.Equal, "hello")
+	this.So(after, should.Equal, "hello")
+}
+func (this *ReloadFixture) TestReloadWhileRequestInFlight_ListenAwaitsPreviousHTTPServer() {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var completed atomic.Bool
+	this.server = New(
+		Options.ListenAddress("127.0.0.1:0"),
+		Options.ListenAdapter(func(listener net.Listener) net.Listener {
+			this.address <- listener.Addr().String()
+			return listener
+		}),
+		Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			close(entered)
+			<-release
+			completed.Store(true)
+		})),
+		Options.Logger(this),
+	).(*defaultServer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		this.server.Listen()
+	}()
+	go this.get(<-this.address)
+	<-entered
+
+	err := this.server.Reload(Options.IdleConnectionTimeout(time.Second * 10))
+	_ = this.server.Close()
+	time.AfterFunc(time.Millisecond*10, func() { close(release) })
+	<-done
+
+	this.So(err, should.BeNil)
+	this.So(completed.Load(), should.BeTrue)
+}
+func (this *ReloadFixture) get(address string) string {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	response, err := client.Get("http://" + address + "/")
+	if err != nil {
+		return err.Error()
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, _ := io.ReadAll(response.Body)
+	return string(body)
+}
+
+func (this *ReloadFixture) Printf(format string, args ...any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.logged = append(this.logged, fmt.Sprintf(format, args...))
+}
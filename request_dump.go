@@ -0,0 +1,276 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// requestRedaction masks the sensitive parts of a request dumped once a panic has been recovered (see
+// Options.DumpRedactHeaders and the related options) and limits the size of the body dumped.
+type requestRedaction struct {
+	deniedHeaders  []string // canonical
+	allowedHeaders []string // canonical, empty allows every header not denied
+	queryNames     []string // lower case
+	fieldNames     []string // lower case
+	jsonFields     *regexp.Regexp
+	maxBodySize    int
+}
+
+func newRequestRedaction(config configuration) requestRedaction {
+	this := requestRedaction{
+		deniedHeaders:  canonicalHeaderKeys(config.DumpRedactHeaders),
+		allowedHeaders: canonicalHeaderKeys(config.DumpAllowHeaders),
+		queryNames:     lowerCase(config.DumpRedactQuery),
+		fieldNames:     lowerCase(config.DumpRedactFields),
+		maxBodySize:    config.DumpMaxBodySize,
+	}
+
+	if len(this.fieldNames) > 0 {
+		quoted := make([]string, 0, len(this.fieldNames))
+		for _, name := range this.fieldNames {
+			quoted = append(quoted, regexp.QuoteMeta(name))
+		}
+		// matches the (possibly truncated) scalar value of each field named, e.g. "password": "hunter2", in a body which
+		// isn't well-formed (see redactJSON)
+		this.jsonFields = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+
+	return this
+}
+
+// dump writes the request, as it would appear on the wire, with sensitive values redacted. Should the body be unreadable
+// only the request line and headers are written along with the error.
+func (this requestRedaction) dump(request *http.Request) (string, error) {
+	clone := request.Clone(request.Context())
+	clone.Header = this.redactHeader(request.Header)
+	clone.URL.RawQuery = redactPairs(request.URL.RawQuery, this.queryNames)
+	clone.RequestURI = "" // dumped from the (redacted) URL instead
+
+	body, truncated, err := this.readBody(request)
+	if err != nil {
+		buffer := new(bytes.Buffer)
+		_, _ = fmt.Fprintf(buffer, "%s %s HTTP/%d.%d\r\n", clone.Method, clone.URL.RequestURI(), clone.ProtoMajor, clone.ProtoMinor)
+		_ = clone.Header.WriteSubset(buffer, nil)
+		return buffer.String(), err
+	}
+
+	clone.Body = io.NopCloser(bytes.NewReader(this.redactBody(request.Header.Get("Content-Type"), body)))
+	raw, err := httputil.DumpRequest(clone, true)
+	if truncated {
+		raw = fmt.Appendf(raw, "... [body truncated to %d bytes]", this.maxBodySize)
+	}
+	return string(raw), err
+}
+func (this requestRedaction) readBody(request *http.Request) (body []byte, truncated bool, err error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, false, nil
+	}
+
+	captured, isCaptured := request.Body.(*capturedBody) // any part already consumed by the handler was captured
+	if !isCaptured {
+		captured = newCapturedBody(request.Body, this.maxBodySize)
+	}
+
+	err = captured.drain()
+	if closeErr := request.Body.Close(); err == nil {
+		err = closeErr
+	}
+	if isCaptured {
+		err = nil // e.g. the handler closed the body once consumed, the remainder (if any) is unavailable
+	}
+
+	body = captured.buffer
+	if this.maxBodySize > 0 && len(body) > this.maxBodySize {
+		body, truncated = body[:this.maxBodySize], true
+	}
+	return body, truncated, err
+}
+
+func (this requestRedaction) redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		canonical := http.CanonicalHeaderKey(name)
+		if slices.Contains(this.deniedHeaders, canonical) ||
+			(len(this.allowedHeaders) > 0 && !slices.Contains(this.allowedHeaders, canonical)) {
+			redacted[name] = []string{redactedValue}
+		} else {
+			redacted[name] = values
+		}
+	}
+	return redacted
+}
+func (this requestRedaction) redactBody(contentType string, body []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return []byte(redactPairs(string(body), this.fieldNames))
+	case this.jsonFields != nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")):
+		if redacted, ok := redactJSON(body, this.fieldNames); ok {
+			return redacted
+		}
+		return this.jsonFields.ReplaceAll(body, []byte(`${1}"`+redactedValue+`"`)) // e.g. a truncated body
+	default:
+		return body
+	}
+}
+
+// redactJSON masks the complete value (e.g. every element of an array) of each member whose name is among those
+// provided, leaving the remainder of the body as is. It reports false unless the body is a single well-formed value.
+func redactJSON(body []byte, names []string) ([]byte, bool) {
+	this := &jsonRedaction{decoder: json.NewDecoder(bytes.NewReader(body)), body: body, names: names}
+	if err := this.value(); err != nil {
+		return nil, false
+	} else if _, err = this.decoder.Token(); err != io.EOF {
+		return nil, false
+	}
+	return append(this.redacted, body[this.copied:]...), true
+}
+
+type jsonRedaction struct {
+	decoder  *json.Decoder
+	body     []byte
+	names    []string // lower case
+	redacted []byte
+	copied   int64
+}
+
+func (this *jsonRedaction) value() error {
+	token, err := this.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for err == nil && this.decoder.More() {
+			if token, err = this.decoder.Token(); err != nil {
+				return err
+			} else if name, _ := token.(string); slices.Contains(this.names, strings.ToLower(name)) {
+				err = this.mask()
+			} else {
+				err = this.value()
+			}
+		}
+	case json.Delim('['):
+		for err == nil && this.decoder.More() {
+			err = this.value()
+		}
+	default:
+		return nil // a scalar
+	}
+
+	if err == nil {
+		_, err = this.decoder.Token() // closing delimiter
+	}
+	return err
+}
+func (this *jsonRedaction) mask() error {
+	var value json.RawMessage
+	if err := this.decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	end := this.decoder.InputOffset()
+	this.redacted = append(this.redacted, this.body[this.copied:end-int64(len(value))]...)
+	this.redacted = append(this.redacted, `"`+redactedValue+`"`...)
+	this.copied = end
+	return nil
+}
+
+// capturedBody retains the bytes read from the request body (see Options.CaptureRequestBody), up to one byte more than
+// the maximum size dumped such that truncation can be detected.
+type capturedBody struct {
+	io.ReadCloser
+	buffer []byte
+	limit  int // zero is unlimited
+}
+
+func newCapturedBody(body io.ReadCloser, maxBodySize int) *capturedBody {
+	this := &capturedBody{ReadCloser: body}
+	if maxBodySize > 0 {
+		this.limit = maxBodySize + 1
+	}
+	return this
+}
+
+func (this *capturedBody) Read(buffer []byte) (int, error) {
+	n, err := this.ReadCloser.Read(buffer)
+	captured := buffer[:n]
+	if this.limit > 0 {
+		captured = captured[:min(len(captured), this.limit-len(this.buffer))]
+	}
+	this.buffer = append(this.buffer, captured...)
+	return n, err
+}
+
+// drain reads the remainder of the body until either the end of the body or the limit is reached.
+func (this *capturedBody) drain() error {
+	chunk := make([]byte, 1024*32)
+	for this.limit == 0 || len(this.buffer) < this.limit {
+		if _, err := this.Read(chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactPairs masks the value of each URL-encoded name=value pair (e.g. a query string or form body) whose name is among
+// those provided (in lower case), preserving the order and encoding of every other pair.
+func redactPairs(raw string, names []string) string {
+	if len(raw) == 0 || len(names) == 0 {
+		return raw
+	}
+
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, _, found := strings.Cut(pair, "=")
+		name := key
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			name = unescaped
+		}
+		if found && slices.Contains(names, strings.ToLower(name)) {
+			pairs[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaderKeys(names []string) (keys []string) {
+	for _, name := range names {
+		keys = append(keys, http.CanonicalHeaderKey(name))
+	}
+	return keys
+}
+func lowerCase(values []string) (lowered []string) {
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+	return lowered
+}
+
+const redactedValue = "[REDACTED]"
+
+// maxCapturedBodySize bounds the body retained by CaptureRequestBody should DumpMaxBodySize be unlimited, which Validate
+// reports as conflicting.
+const maxCapturedBodySize = 1024 * 64
+
+var (
+	defaultRedactedHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token", "X-Csrf-Token",
+	}
+	defaultRedactedFields = []string{
+		"password", "passwd", "secret", "client_secret", "token", "access_token", "refresh_token", "id_token", "api_key",
+		"apikey", "credit_card", "card_number", "cvv", "ssn",
+	}
+)
@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestRequestRedactionFixture(t *testing.T) {
+	gunit.Run(new(RequestRedactionFixture), t)
+}
+
+type RequestRedactionFixture struct {
+	*gunit.Fixture
+
+	options []option
+}
+
+func (this *RequestRedactionFixture) dump(method, target, contentType, body string, headers ...string) string {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(contentType) > 0 {
+		request.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+
+	var config configuration
+	Options.apply(this.options...)(&config)
+	dumped, err := newRequestRedaction(config).dump(request)
+	this.So(err, should.BeNil)
+	return dumped
+}
+
+func (this *RequestRedactionFixture) TestDefaultHeadersRedacted() {
+	dumped := this.dump("GET", "/", "", "", "Authorization", "Bearer abc", "Cookie", "session=xyz", "Accept", "text/plain")
+
+	this.So(dumped, should.ContainSubstring, "Authorization: [REDACTED]\r\n")
+	this.So(dumped, should.ContainSubstring, "Cookie: [REDACTED]\r\n")
+	this.So(dumped, should.ContainSubstring, "Accept: text/plain\r\n")
+	this.So(dumped, should.NotContainSubstring, "abc")
+	this.So(dumped, should.NotContainSubstring, "xyz")
+}
+func (this *RequestRedactionFixture) TestAllowedHeaders_OthersRedacted() {
+	this.options = append(this.options, Options.DumpAllowHeaders("accept", "authorization"))
+
+	dumped := this.dump("GET", "/", "", "", "Authorization", "Bearer abc", "Accept", "text/plain", "X-Custom", "value")
+
+	this.So(dumped, should.ContainSubstring, "Authorization: [REDACTED]\r\n") // denied takes precedence
+	this.So(dumped, should.ContainSubstring, "Accept: text/plain\r\n")
+	this.So(dumped, should.ContainSubstring, "X-Custom: [REDACTED]\r\n")
+}
+func (this *RequestRedactionFixture) TestQueryParametersRedacted() {
+	dumped := this.dump("GET", "/path?user=bob&Password=hunter2&api%5Fkey=abc&flag", "", "")
+
+	this.So(dumped, should.StartWith, "GET /path?user=bob&Password=[REDACTED]&api%5Fkey=[REDACTED]&flag HTTP/1.1\r\n")
+}
+func (this *RequestRedactionFixture) TestJSONFieldsRedacted() {
+	dumped := this.dump("POST", "/", "application/json; charset=utf-8",
+		`{"user":"bob","password":"hun\"ter2","nested":{"Token": 1234, "ok": true},"cvv":null}`)
+
+	this.So(dumped, should.EndWith, `{"user":"bob","password":"[REDACTED]","nested":{"Token": "[REDACTED]", "ok": true},"cvv":"[REDACTED]"}`)
+}
+func (this *RequestRedactionFixture) TestJSONArraysAndObjectsRedactedCompletely() {
+	this.options = append(this.options, Options.DumpRedactFields("password", "card"))
+
+	dumped := this.dump("POST", "/", "application/json",
+		`[{"password": ["a", "b"], "card": {"number": "4111", "cvv": 123}, "user": "bob"}, {"PASSWORD": 42}]`)
+
+	this.So(dumped, should.EndWith, `[{"password": "[REDACTED]", "card": "[REDACTED]", "user": "bob"}, {"PASSWORD": "[REDACTED]"}]`)
+}
+func (this *RequestRedactionFixture) TestFormFieldsRedacted() {
+	this.options = append(this.options, Options.DumpRedactFields("pin"))
+
+	dumped := this.dump("POST", "/", "application/x-www-form-urlencoded", "user=bob&PIN=1234&password=hunter2")
+
+	this.So(dumped, should.EndWith, "user=bob&PIN=[REDACTED]&password=hunter2")
+}
+func (this *RequestRedactionFixture) TestUnrecognizedBodyNotRedacted() {
+	dumped := this.dump("POST", "/", "text/plain", `"password":"hunter2"`)
+
+	this.So(dumped, should.EndWith, `"password":"hunter2"`)
+}
+func (this *RequestRedactionFixture) TestBodyTruncated() {
+	this.options = append(this.options, Options.DumpMaxBodySize(28))
+
+	dumped := this.dump("POST", "/", "application/json", `{"user":"bob","password":"hunter2"}`)
+
+	this.So(dumped, should.EndWith, `{"user":"bob","password":"[REDACTED]"... [body truncated to 28 bytes]`)
+}
+func (this *RequestRedactionFixture) TestBodyWithinLimitNotTruncated() {
+	this.options = append(this.options, Options.DumpMaxBodySize(8))
+
+	dumped := this.dump("POST", "/", "text/plain", "12345678")
+
+	this.So(dumped, should.EndWith, "\r\n\r\n12345678")
+}
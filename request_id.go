@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestIDFromContext returns the ID of the request being handled (see Options.RequestID), or an empty string when
+// request IDs aren't enabled.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDHandler identifies each request using the value of the RequestIDHeader, when valid, otherwise a generated ID.
+// The ID is placed on the request context and written to the same header of the response before the handler is called
+// such that it's also included in the response to a recovered panic.
+type requestIDHandler struct {
+	http.Handler
+	header    string
+	maxLength int
+	generate  func() string
+}
+
+func newRequestIDHandler(handler http.Handler, config configuration) *requestIDHandler {
+	this := &requestIDHandler{
+		Handler:   handler,
+		header:    http.CanonicalHeaderKey(config.RequestIDHeader),
+		maxLength: config.RequestIDMaxLength,
+		generate:  config.RequestIDGenerator,
+	}
+	if this.generate == nil {
+		this.generate = newUUIDv7
+	}
+	return this
+}
+
+func (this *requestIDHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	id := request.Header.Get(this.header)
+	if !this.valid(id) {
+		id = this.generate()
+	}
+
+	response.Header().Set(this.header, id)
+	this.Handler.ServeHTTP(response, request.WithContext(context.WithValue(request.Context(), requestIDKey{}, id)))
+}
+
+// valid accepts IDs of printable, unreserved characters such that a client can't inject content into the logs.
+func (this *requestIDHandler) valid(id string) bool {
+	if len(id) == 0 || len(id) > this.maxLength {
+		return false
+	}
+
+	for _, character := range []byte(id) {
+		if !isRequestIDCharacter(character) {
+			return false
+		}
+	}
+	return true
+}
+func isRequestIDCharacter(character byte) bool {
+	return ('a' <= character && character <= 'z') || ('A' <= character && character <= 'Z') ||
+		('0' <= character && character <= '9') || strings.IndexByte("-_.:/+=@", character) >= 0
+}
+
+// newUUIDv7 generates an RFC 9562 version 7 UUID, i.e. a millisecond timestamp followed by random bits, such that IDs
+// sort in the order in which requests arrived.
+func newUUIDv7() string {
+	var id [16]byte
+	_, _ = rand.Read(id[6:])
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(time.Now().UnixMilli()))
+	copy(id[:6], timestamp[2:])
+	id[6] = id[6]&0x0f | 0x70 // version 7
+	id[8] = id[8]&0x3f | 0x80 // RFC 9562 variant
+
+	encoded := hex.EncodeToString(id[:])
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
+
+type requestIDKey struct{}
@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestRequestIDFixture(t *testing.T) {
+	gunit.Run(new(RequestIDFixture), t)
+}
+
+type RequestIDFixture struct {
+	*gunit.Fixture
+
+	response *httptest.ResponseRecorder
+	request  *http.Request
+	handled  string
+	panicked bool
+	logged   []string
+}
+
+func (this *RequestIDFixture) Setup() {
+	this.response = httptest.NewRecorder()
+	this.request = httptest.NewRequest("GET", "/", nil)
+}
+
+func (this *RequestIDFixture) TestMissingRequestID_UUIDv7Generated() {
+	this.serve()
+
+	this.So(this.handled, should.NotBeBlank)
+	this.So(uuidV7Pattern.MatchString(this.handled), should.BeTrue)
+	this.So(this.response.Header().Get("X-Request-ID"), should.Equal, this.handled)
+}
+func (this *RequestIDFixture) TestValidRequestID_Propagated() {
+	this.request.Header.Set("X-Request-ID", "upstream-1234:abc")
+
+	this.serve()
+
+	this.So(this.handled, should.Equal, "upstream-1234:abc")
+	this.So(this.response.Header().Get("X-Request-ID"), should.Equal, "upstream-1234:abc")
+}
+func (this *RequestIDFixture) TestInvalidRequestID_Replaced() {
+	for _, invalid := range []string{"has space", "new\nline", "<script>", strings.Repeat("a", 129)} {
+		this.response = httptest.NewRecorder()
+		this.request.Header.Set("X-Request-ID", invalid)
+
+		this.serve()
+
+		this.So(uuidV7Pattern.MatchString(this.handled), should.BeTrue)
+	}
+}
+func (this *RequestIDFixture) TestCustomHeaderAndGenerator_Used() {
+	this.request.Header.Set("X-Request-ID", "ignored")
+
+	this.serve(
+		Options.RequestIDHeader("x-correlation-id"),
+		Options.RequestIDMaxLength(8),
+		Options.RequestIDGenerator(func() string { return "generated" }),
+	)
+
+	this.So(this.handled, should.Equal, "generated")
+	this.So(this.response.Header().Get("X-Correlation-Id"), should.Equal, "generated")
+}
+func (this *RequestIDFixture) TestDisabled_NoRequestID() {
+	var config configuration
+	Options.apply(Options.Handler(this))(&config)
+
+	config.Handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.handled, should.BeBlank)
+	this.So(this.response.Header().Get("X-Request-ID"), should.BeBlank)
+}
+func (this *RequestIDFixture) TestPanicRecovered_RequestIDUsedAsCorrelationID() {
+	this.request.Header.Set("X-Request-ID", "request-1")
+	this.panicked = true
+
+	this.serve(Options.Logger(this))
+
+	this.So(this.response.Code, should.Equal, http.StatusInternalServerError)
+	this.So(this.response.Header().Get("X-Request-ID"), should.Equal, "request-1")
+	this.So(this.response.Body.String(), should.ContainSubstring, "Correlation ID: request-1")
+	this.So(this.logged, should.HaveLength, 1)
+	this.So(this.logged[0], should.ContainSubstring, "[correlation ID: request-1]")
+}
+func (this *RequestIDFixture) TestAccessLog_RequestIDAppended() {
+	this.request.Header.Set("X-Request-ID", "request-2")
+
+	this.serve(Options.AccessLogger(this), Options.AccessLogFormat(AccessLogCommon))
+	this.serve(Options.AccessLogger(this), Options.AccessLogFormat(AccessLogJSON), Options.AccessLogFields(AccessLogFieldStatus))
+
+	this.So(this.logged, should.HaveLength, 2)
+	this.So(this.logged[0], should.EndWith, ` 200 - "request-2"`)
+	this.So(this.logged[1], should.Equal, `{"status":200,"request_id":"request-2"}`)
+}
+
+func (this *RequestIDFixture) serve(options ...option) {
+	var config configuration
+	Options.apply(append([]option{Options.Handler(this), Options.RequestID(true)}, options...)...)(&config)
+	config.Handler.ServeHTTP(this.response, this.request)
+}
+func (this *RequestIDFixture) ServeHTTP(_ http.ResponseWriter, request *http.Request) {
+	this.handled = RequestIDFromContext(request.Context())
+	if this.panicked {
+		panic("boom")
+	}
+}
+func (this *RequestIDFixture) Printf(format string, args ...any) {
+	this.logged = append(this.logged, fmt.Sprintf(format, args...))
+}
+
+var uuidV7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
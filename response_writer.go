@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// trackingResponseWriter records the status code and number of body bytes written to the response. Optional
+// capabilities of the underlying http.ResponseWriter are reached using http.ResponseController (see Unwrap), while
+// http.Flusher and http.Hijacker are only implemented by the writer passed to the handler (see exposed) when the
+// underlying http.ResponseWriter implements them, e.g. HTTP/2 responses can't be hijacked.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	written  int64
+	hijacked bool
+}
+
+func newTrackingResponseWriter(response http.ResponseWriter) *trackingResponseWriter {
+	return &trackingResponseWriter{ResponseWriter: response}
+}
+
+func (this *trackingResponseWriter) WriteHeader(status int) {
+	if this.status == 0 && (status >= http.StatusOK || status == http.StatusSwitchingProtocols) {
+		this.status = status // informational (1xx) responses precede the final status
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+func (this *trackingResponseWriter) Write(buffer []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	n, err := this.ResponseWriter.Write(buffer)
+	this.written += int64(n)
+	return n, err
+}
+
+// ReadFrom preserves the io.ReaderFrom of the underlying http.ResponseWriter, e.g. such that files are sent using
+// sendfile.
+func (this *trackingResponseWriter) ReadFrom(reader io.Reader) (int64, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if from, ok := this.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = from.ReadFrom(reader)
+	} else {
+		n, err = io.Copy(this.ResponseWriter, reader)
+	}
+	this.written += n
+	return n, err
+}
+func (this *trackingResponseWriter) FlushError() error {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	return http.NewResponseController(this.ResponseWriter).Flush()
+}
+func (this *trackingResponseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffer, err := http.NewResponseController(this.ResponseWriter).Hijack()
+	this.hijacked = err == nil
+	return conn, buffer, err
+}
+func (this *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+func (this *trackingResponseWriter) Status() int {
+	if this.status == 0 && !this.hijacked {
+		return http.StatusOK // net/http writes the implicit status once the handler returns
+	}
+	return this.status
+}
+
+// exposed returns the writer passed to the handler, which implements http.Flusher and http.Hijacker only when the
+// underlying http.ResponseWriter does.
+func (this *trackingResponseWriter) exposed() http.ResponseWriter {
+	_, flusher := this.ResponseWriter.(http.Flusher)
+	_, hijacker := this.ResponseWriter.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return flushingHijackingResponseWriter{this}
+	case flusher:
+		return flushingResponseWriter{this}
+	case hijacker:
+		return hijackingResponseWriter{this}
+	default:
+		return this
+	}
+}
+
+type flushingResponseWriter struct{ *trackingResponseWriter }
+
+func (this flushingResponseWriter) Flush() { _ = this.FlushError() }
+
+type hijackingResponseWriter struct{ *trackingResponseWriter }
+
+func (this hijackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return this.hijack()
+}
+
+type flushingHijackingResponseWriter struct{ *trackingResponseWriter }
+
+func (this flushingHijackingResponseWriter) Flush() { _ = this.FlushError() }
+func (this flushingHijackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return this.hijack()
+}
@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestResponseWriterFixture(t *testing.T) {
+	gunit.Run(new(ResponseWriterFixture), t)
+}
+
+type ResponseWriterFixture struct {
+	*gunit.Fixture
+}
+
+func (this *ResponseWriterFixture) TestFlushableResponse_OnlyFlusherExposed() {
+	recorder := httptest.NewRecorder()
+	tracked := newTrackingResponseWriter(recorder)
+
+	exposed := tracked.exposed()
+	_, flusher := exposed.(http.Flusher)
+	_, hijacker := exposed.(http.Hijacker)
+	exposed.(http.Flusher).Flush()
+
+	this.So(flusher, should.BeTrue)
+	this.So(hijacker, should.BeFalse)
+	this.So(recorder.Flushed, should.BeTrue)
+	this.So(tracked.Status(), should.Equal, http.StatusOK)
+}
+func (this *ResponseWriterFixture) TestResponseWithoutCapabilities_NeitherExposedAndFlushErrorReported() {
+	tracked := newTrackingResponseWriter(&minimalResponseWriter{})
+
+	exposed := tracked.exposed()
+	_, flusher := exposed.(http.Flusher)
+	_, hijacker := exposed.(http.Hijacker)
+
+	this.So(flusher, should.BeFalse)
+	this.So(hijacker, should.BeFalse)
+	this.So(errors.Is(http.NewResponseController(exposed).Flush(), http.ErrNotSupported), should.BeTrue)
+}
+func (this *ResponseWriterFixture) TestHijackableResponse_HijackerExposedAndTracked() {
+	tracked := newTrackingResponseWriter(&hijackableResponseWriter{})
+
+	_, _, err := tracked.exposed().(http.Hijacker).Hijack()
+
+	this.So(err, should.BeNil)
+	this.So(tracked.Status(), should.Equal, 0)
+}
+func (this *ResponseWriterFixture) TestReadFrom_ForwardedToUnderlyingResponse() {
+	response := &minimalResponseWriter{}
+	tracked := newTrackingResponseWriter(response)
+
+	n, err := tracked.exposed().(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+
+	this.So(err, should.BeNil)
+	this.So(n, should.Equal, 5)
+	this.So(response.readFrom, should.Equal, "hello")
+	this.So(tracked.written, should.Equal, 5)
+	this.So(tracked.Status(), should.Equal, http.StatusOK)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type minimalResponseWriter struct{ readFrom string }
+
+func (this *minimalResponseWriter) Header() http.Header              { return http.Header{} }
+func (this *minimalResponseWriter) Write(buffer []byte) (int, error) { return len(buffer), nil }
+func (this *minimalResponseWriter) WriteHeader(int)                  {}
+func (this *minimalResponseWriter) ReadFrom(reader io.Reader) (int64, error) {
+	raw, err := io.ReadAll(reader)
+	this.readFrom += string(raw)
+	return int64(len(raw)), err
+}
+
+type hijackableResponseWriter struct{ minimalResponseWriter }
+
+func (this *hijackableResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	server, client := net.Pipe()
+	_ = client.Close()
+	return server, nil, nil
+}
@@ -3,58 +3,82 @@ package httpserver
 import (
 	"context"
 	"crypto/tls"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type defaultServer struct {
-	config          configuration
-	hardContext     context.Context
-	hardShutdown    context.CancelFunc
-	softContext     context.Context
-	softShutdown    context.CancelFunc
-	shutdownTimeout time.Duration
-	forcedTimeout   time.Duration
-	listenNetwork   string
-	listenAddress   string
-	listenConfig    listenConfig
-	listenAdapter   func(net.Listener) net.Listener
-	listenReady     func(bool)
-	tlsConfig       *tls.Config
-	httpServer      httpServer
-	logger          logger
+	mutex            sync.Mutex
+	config           configuration
+	hardContext      context.Context
+	hardShutdown     context.CancelFunc
+	softContext      context.Context
+	softShutdown     context.CancelFunc
+	shutdownTimeout  time.Duration
+	forcedTimeout    time.Duration
+	listenNetwork    string
+	listenAddress    string
+	listenConfig     listenConfig
+	listenAdapter    func(net.Listener) net.Listener
+	listenReady      func(bool)
+	tlsConfig        atomic.Pointer[tls.Config]
+	httpServer       httpServer
+	reloadable       bool
+	reloadOptions    func() []option
+	reloadSignals    []os.Signal
+	drains           sync.WaitGroup
+	recoveryHandler  *recoveryHandler
+	accessLogHandler *accessLogHandler
+	tracingHandler   *tracingHandler
+	swapHandler      *swapHandler
+	monitor          monitor
+	traceExporter    SpanExporter
+	logger           logger
 }
 
 func newServer(config configuration) ListenCloser {
-	softContext, softShutdown := context.WithCancel(config.Context)
-	return &defaultServer{
-		config:          config,
-		hardContext:     config.Context,
-		hardShutdown:    config.ContextShutdown,
-		softContext:     softContext,
-		softShutdown:    softShutdown,
-		shutdownTimeout: config.ShutdownTimeout,
-		forcedTimeout:   config.ForceShutdownTimeout,
-		listenNetwork:   config.ListenNetwork,
-		listenAddress:   config.ListenAddress,
-		listenConfig:    config.ListenConfig,
-		listenAdapter:   config.ListenAdapter,
-		listenReady:     config.ListenReady,
-		tlsConfig:       config.TLSConfig,
-		httpServer:      config.HTTPServer,
-		logger:          config.Logger,
+	this := &defaultServer{
+		config:           config,
+		hardContext:      config.Context,
+		hardShutdown:     config.ContextShutdown,
+		softContext:      config.SoftContext,
+		softShutdown:     config.SoftShutdown,
+		shutdownTimeout:  config.ShutdownTimeout,
+		forcedTimeout:    config.ForceShutdownTimeout,
+		listenNetwork:    config.ListenNetwork,
+		listenAddress:    config.ListenAddress,
+		listenConfig:     config.ListenConfig,
+		listenAdapter:    config.ListenAdapter,
+		listenReady:      config.ListenReady,
+		httpServer:       config.HTTPServer,
+		reloadable:       config.ReloadableHTTPServer,
+		reloadOptions:    config.ReloadOptions,
+		reloadSignals:    config.ReloadSignals,
+		recoveryHandler:  config.RecoveryHandler,
+		accessLogHandler: config.AccessLogHandler,
+		tracingHandler:   config.TracingHandler,
+		swapHandler:      config.SwapHandler,
+		monitor:          config.Monitor,
+		traceExporter:    config.TraceExporter,
+		logger:           config.Logger,
 	}
+	this.tlsConfig.Store(config.TLSConfig)
+	return this
 }
 
 func (this *defaultServer) Listen() {
 	waiter := &sync.WaitGroup{}
-	waiter.Add(2)
+	waiter.Add(3)
 	defer waiter.Wait()
 
 	go this.listen(waiter)
 	go this.watchShutdown(waiter)
+	go this.watchReload(waiter)
 }
 func (this *defaultServer) listen(waiter *sync.WaitGroup) {
 	defer waiter.Done()
@@ -63,34 +87,78 @@ func (this *defaultServer) listen(waiter *sync.WaitGroup) {
 		return
 	}
 
+	if err := this.config.ListenAddressError; err != nil {
+		this.notifyReady(false)
+		this.notifyListenFailed(err)
+		logEvent(this.logger, slog.LevelWarn, this.listenAttrs(slog.Any("error", err)), "Unable to listen on [%s://%s]: [%s]", this.listenNetwork, this.listenAddress, err)
+		return
+	}
+
 	if listener, err := this.bindListener(); err != nil {
-		this.logger.Printf("[WARN] Unable to listen on [%s://%s]: [%s]", this.listenNetwork, this.listenAddress, err)
+		logEvent(this.logger, slog.LevelWarn, this.listenAttrs(slog.Any("error", err)), "Unable to listen on [%s://%s]: [%s]", this.listenNetwork, this.listenAddress, err)
 	} else if err = this.serve(listener); err != nil {
-		this.logger.Printf("[WARN] Unable to listen on [%s://%s]: [%s]", this.listenNetwork, this.listenAddress, err)
+		logEvent(this.logger, slog.LevelWarn, this.listenAttrs(slog.Any("error", err)), "Unable to listen on [%s://%s]: [%s]", this.listenNetwork, this.listenAddress, err)
 	}
 }
 func (this *defaultServer) bindListener() (net.Listener, error) {
 	listener, err := this.listenConfig.Listen(this.softContext, this.listenNetwork, this.listenAddress)
 	if err != nil {
 		this.notifyReady(false)
+		this.notifyListenFailed(err)
 		return nil, err
 	}
 
+	listener = newLimitListener(listener, this.config)
+
 	if this.listenAdapter != nil {
 		listener = this.listenAdapter(listener)
 	}
 
-	if this.tlsConfig != nil {
-		listener = tls.NewListener(listener, this.tlsConfig)
+	if this.tlsConfig.Load() != nil {
+		listener = tls.NewListener(listener, &tls.Config{GetConfigForClient: this.currentTLSConfig})
 	}
 
 	this.notifyReady(true)
+	if monitor, ok := this.monitor.(listenMonitor); ok {
+		monitor.ListenSucceeded(this.listenNetwork, this.listenAddress)
+	}
 	return listener, nil
 }
 func (this *defaultServer) serve(listener net.Listener) error {
-	this.logger.Printf("[INFO] Listening for HTTP traffic on [%s://%s]...", this.listenNetwork, this.listenAddress)
+	logEvent(this.logger, slog.LevelInfo, this.listenAttrs(), "Listening for HTTP traffic on [%s://%s]...", this.listenNetwork, this.listenAddress)
+
+	if !this.reloadable {
+		return ignoreServerClosed(this.currentHTTPServer().Serve(listener))
+	}
 
-	err := this.httpServer.Serve(listener)
+	shared := newHandoffListener(listener) // allows a reloaded http.Server to take over the bound socket
+	defer func() { _ = shared.Close() }()
+
+	for {
+		server := this.currentHTTPServer()
+		if err := server.Serve(shared.View()); err != http.ErrServerClosed {
+			return err
+		} else if server == this.currentHTTPServer() {
+			return nil // not replaced during reload, so the server is shutting down
+		}
+	}
+}
+func (this *defaultServer) currentHTTPServer() httpServer {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.httpServer
+}
+func (this *defaultServer) currentTLSConfig(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	current := this.tlsConfig.Load()
+	if current.GetConfigForClient == nil {
+		return current, nil
+	} else if config, err := current.GetConfigForClient(hello); config != nil || err != nil {
+		return config, err
+	} else {
+		return current, nil
+	}
+}
+func ignoreServerClosed(err error) error {
 	if err == http.ErrServerClosed {
 		return nil
 	}
@@ -104,36 +172,111 @@ func (this *defaultServer) notifyReady(ready bool) {
 	this.listenReady(ready)
 	this.listenReady = nil
 }
+func (this *defaultServer) listenAttrs(attrs ...slog.Attr) []slog.Attr {
+	return append([]slog.Attr{slog.String("network", this.listenNetwork), slog.String("address", this.listenAddress)}, attrs...)
+}
+func (this *defaultServer) notifyListenFailed(err error) {
+	if monitor, ok := this.monitor.(listenMonitor); ok {
+		monitor.ListenFailed(this.listenNetwork, this.listenAddress, err)
+	}
+}
 func (this *defaultServer) watchShutdown(waiter *sync.WaitGroup) {
 	var shutdownError error
+	var started time.Time
 	defer func() {
 		defer waiter.Done()
 		this.hardShutdown()
-		this.awaitOutstandingRequests(shutdownError)
+		this.awaitOutstandingRequests(shutdownError, started)
+		this.flushSpans()
 	}()
 
-	<-this.softContext.Done()                                                  // waiting for soft context shutdown to occur
+	<-this.softContext.Done() // waiting for soft context shutdown to occur
+	started = time.Now()
+	this.notifyShutdownPhase(ShutdownStarted, started)
 	ctx, cancel := context.WithTimeout(this.hardContext, this.shutdownTimeout) // wait until shutdownTimeout for shutdown
 	defer cancel()
-	this.logger.Printf("[INFO] Shutting down HTTP server [%s]...", this.listenAddress)
-	shutdownError = this.httpServer.Shutdown(ctx)
+	logEvent(this.logger, slog.LevelInfo, this.listenAttrs(), "Shutting down HTTP server [%s]...", this.listenAddress)
+	shutdownError = this.currentHTTPServer().Shutdown(ctx)
+	this.drains.Wait() // http.Servers replaced by Reload drain their own connections within the same timeout
+	if shutdownError == nil && this.recoveryHandler != nil {
+		shutdownError = this.recoveryHandler.awaitTasks(ctx) // tasks started by handlers using Go
+	}
 }
-func (this *defaultServer) awaitOutstandingRequests(err error) {
-	defer this.logger.Printf("[INFO] HTTP server shutdown complete. [%s]", this.listenAddress)
+func (this *defaultServer) awaitOutstandingRequests(err error, started time.Time) {
+	defer logEvent(this.logger, slog.LevelInfo, this.listenAttrs(), "HTTP server shutdown complete. [%s]", this.listenAddress)
+	defer this.notifyShutdownPhase(ShutdownCompleted, started)
 
 	if err == nil {
+		this.notifyShutdownPhase(ShutdownDrained, started)
 		return
 	}
 
 	// 1+ outstanding request(s) is/are still being processed, if the request.Context() cancellation is considered by
 	// the http.Handler, let's give a moment longer to complete the run through the configured http.Handler pipeline.
-	this.logger.Printf("[INFO] HTTP request(s) in flight after server shutdown, waiting for %s...", this.forcedTimeout)
+	this.notifyShutdownPhase(ShutdownTimedOut, started)
+	logEvent(this.logger, slog.LevelInfo, this.listenAttrs(slog.Duration("timeout", this.forcedTimeout)), "HTTP request(s) in flight after server shutdown, waiting for %s...", this.forcedTimeout)
 	ctx, cancel := context.WithTimeout(context.Background(), this.forcedTimeout)
 	defer cancel()
 	<-ctx.Done()
 }
+func (this *defaultServer) flushSpans() {
+	flusher, ok := this.traceExporter.(spanFlusher)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), this.shutdownTimeout)
+	defer cancel()
+	if err := flusher.Flush(ctx); err != nil {
+		logEvent(this.logger, slog.LevelWarn, this.listenAttrs(slog.Any("error", err)), "Unable to flush trace spans: [%s]", err)
+	}
+}
+func (this *defaultServer) notifyShutdownPhase(phase ShutdownPhase, started time.Time) {
+	if monitor, ok := this.monitor.(shutdownMonitor); ok {
+		monitor.ShutdownPhaseEntered(phase, time.Since(started))
+	}
+}
+
+func (this *defaultServer) SwapHandler(handler http.Handler) uint64 {
+	generation := this.swapHandler.Swap(handler)
+	logEvent(this.logger, slog.LevelInfo, this.listenAttrs(slog.Uint64("generation", generation)), "HTTP handler swapped, now serving generation [%d]. [%s]", generation, this.listenAddress)
+	return generation
+}
 
 func (this *defaultServer) Close() error {
 	this.softShutdown()
 	return nil
 }
+
+// ShutdownPhase identifies a stage of shutting down the server as reported to a monitor.
+type ShutdownPhase uint8
+
+const (
+	// ShutdownStarted indicates the listener is closing and in-flight requests are given ShutdownTimeout to complete.
+	ShutdownStarted ShutdownPhase = iota
+
+	// ShutdownDrained indicates every in-flight request completed within ShutdownTimeout.
+	ShutdownDrained
+
+	// ShutdownTimedOut indicates requests remained in flight after ShutdownTimeout, the server context is cancelled
+	// and those requests are given ForceShutdownTimeout longer to complete.
+	ShutdownTimedOut
+
+	// ShutdownCompleted indicates the server has stopped.
+	ShutdownCompleted
+)
+
+func (this ShutdownPhase) String() string {
+	switch this {
+	case ShutdownStarted:
+		return "started"
+	case ShutdownDrained:
+		return "drained"
+	case ShutdownTimedOut:
+		return "timed-out"
+	case ShutdownCompleted:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
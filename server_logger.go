@@ -3,12 +3,107 @@ package httpserver
 import (
 	"bytes"
 	"log"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
-type serverLogger struct{ logger }
+// ServerLogClass identifies the kind of message written by the http.Server to its ErrorLog.
+type ServerLogClass uint8
 
-func newServerLogger(inner logger) *log.Logger {
-	return log.New(&serverLogger{logger: inner}, "", 0)
+const (
+	// ServerLogOther is any message not otherwise classified.
+	ServerLogOther ServerLogClass = iota
+
+	// ServerLogTLSHandshake is a failed TLS handshake, commonly caused by scanners and health checks.
+	ServerLogTLSHandshake
+
+	// ServerLogHeaderTooLarge is a request or response header exceeding the allowed size.
+	ServerLogHeaderTooLarge
+
+	// ServerLogAcceptError is a temporary failure accepting a connection after which accepting is retried.
+	ServerLogAcceptError
+
+	// ServerLogSuperfluousWriteHeader is a handler calling WriteHeader more than once.
+	ServerLogSuperfluousWriteHeader
+
+	// ServerLogQuerySemicolon is a request URL containing a semicolon in its query (see golang.org/issue/25192).
+	ServerLogQuerySemicolon
+)
+
+func (this ServerLogClass) String() string {
+	switch this {
+	case ServerLogOther:
+		return "other"
+	case ServerLogTLSHandshake:
+		return "tls-handshake"
+	case ServerLogHeaderTooLarge:
+		return "header-too-large"
+	case ServerLogAcceptError:
+		return "accept"
+	case ServerLogSuperfluousWriteHeader:
+		return "superfluous-write-header"
+	case ServerLogQuerySemicolon:
+		return "query-semicolon"
+	default:
+		return "unknown"
+	}
+}
+
+var serverLogClasses = []ServerLogClass{
+	ServerLogOther, ServerLogTLSHandshake, ServerLogHeaderTooLarge, ServerLogAcceptError, ServerLogSuperfluousWriteHeader,
+	ServerLogQuerySemicolon,
+}
+
+func classifyServerLog(message []byte) ServerLogClass {
+	switch {
+	case bytes.HasPrefix(message, tlsHandshakeErrorPrefix):
+		return ServerLogTLSHandshake
+	case bytes.HasPrefix(message, acceptErrorPrefix):
+		return ServerLogAcceptError
+	case bytes.Contains(message, superfluousWriteHeader):
+		return ServerLogSuperfluousWriteHeader
+	case bytes.HasSuffix(message, querySemicolonSuffix):
+		return ServerLogQuerySemicolon
+	case bytes.Contains(message, headerTooLarge):
+		return ServerLogHeaderTooLarge
+	default:
+		return ServerLogOther
+	}
+}
+
+// serverLogger forwards each message from the http.Server to the ErrorLogger unless its class is suppressed or the
+// rate limit of its class is exceeded, in which case a summary of the messages suppressed is written once the interval
+// concludes.
+type serverLogger struct {
+	logger
+	monitor     tlsHandshakeMonitor
+	counter     serverLogMonitor
+	suppressed  []ServerLogClass
+	rateLimit   int
+	rateWindow  time.Duration
+	mutex       sync.Mutex
+	rateWindows map[ServerLogClass]*serverLogWindow
+}
+type serverLogWindow struct {
+	started    time.Time
+	logged     int
+	suppressed int
+}
+
+func newServerLogger(config configuration) *log.Logger {
+	this := &serverLogger{
+		logger:      config.ErrorLogger,
+		suppressed:  config.ServerLogSuppressed,
+		rateLimit:   config.ServerLogRateLimit,
+		rateWindow:  config.ServerLogRateInterval,
+		rateWindows: make(map[ServerLogClass]*serverLogWindow),
+	}
+	this.monitor, _ = config.Monitor.(tlsHandshakeMonitor)
+	this.counter, _ = config.Monitor.(serverLogMonitor)
+	return log.New(this, "", 0)
 }
 
 func (this *serverLogger) Write(buffer []byte) (int, error) {
@@ -19,13 +114,65 @@ func (this *serverLogger) Write(buffer []byte) (int, error) {
 	}
 
 	buffer = buffer[0 : length-1] // trim trailing line break
+	class := classifyServerLog(buffer)
 
-	if bytes.HasSuffix(buffer, ignoredLogStatements) {
-		return length, nil // no-op
+	attrs := []slog.Attr{slog.String("source", "http.Server"), slog.String("class", class.String())}
+	if class == ServerLogTLSHandshake {
+		remoteAddress, reason, _ := strings.Cut(string(buffer[len(tlsHandshakeErrorPrefix):]), ": ")
+		attrs = append(attrs, slog.String("remote_address", remoteAddress))
+		if this.monitor != nil {
+			this.monitor.TLSHandshakeFailed(remoteAddress, reason)
+		}
 	}
 
-	this.logger.Printf("[WARN] %s", buffer)
+	allowed := !slices.Contains(this.suppressed, class) && this.allow(class)
+	if this.counter != nil {
+		this.counter.ServerLogged(class, !allowed)
+	}
+
+	if allowed {
+		logEvent(this.logger, slog.LevelWarn, attrs, "%s", buffer)
+	}
 	return length, nil
 }
+func (this *serverLogger) allow(class ServerLogClass) bool {
+	if this.rateLimit <= 0 {
+		return true
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	window, found := this.rateWindows[class]
+	if !found || time.Since(window.started) >= this.rateWindow {
+		window = &serverLogWindow{started: time.Now()}
+		this.rateWindows[class] = window
+	}
 
-var ignoredLogStatements = []byte("golang.org/issue/25192") // "http: URL query contains semicolon, which is no longer a supported separator; parts of the query may be stripped when parsed; see golang.org/issue/25192"
+	if window.logged < this.rateLimit {
+		window.logged++
+		return true
+	}
+
+	if window.suppressed++; window.suppressed == 1 {
+		time.AfterFunc(this.rateWindow-time.Since(window.started), func() { this.summarize(class, window) })
+	}
+	return false
+}
+func (this *serverLogger) summarize(class ServerLogClass, window *serverLogWindow) {
+	this.mutex.Lock()
+	suppressed := window.suppressed
+	this.mutex.Unlock()
+
+	attrs := []slog.Attr{slog.String("source", "http.Server"), slog.String("class", class.String()), slog.Int("suppressed", suppressed)}
+	logEvent(this.logger, slog.LevelWarn, attrs, "%d [%s] messages from http.Server suppressed in the last %s (limit %d)",
+		suppressed, class, this.rateWindow, this.rateLimit)
+}
+
+var (
+	tlsHandshakeErrorPrefix = []byte("http: TLS handshake error from ")  // "http: TLS handshake error from 127.0.0.1:1234: EOF"
+	acceptErrorPrefix       = []byte("http: Accept error: ")             // "http: Accept error: accept tcp [::]:80: accept4: too many open files; retrying in 5ms"
+	superfluousWriteHeader  = []byte("superfluous response.WriteHeader") // "http: superfluous response.WriteHeader call from main.handler (main.go:12)"
+	querySemicolonSuffix    = []byte("golang.org/issue/25192")           // "http: URL query contains semicolon, which is no longer a supported separator; parts of the query may be stripped when parsed; see golang.org/issue/25192"
+	headerTooLarge          = []byte("header too large")                 // "http2: ... header too large", "http: request header too large"
+)
@@ -0,0 +1,93 @@
+package httpserver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestServerLoggerFixture(t *testing.T) {
+	gunit.Run(new(ServerLoggerFixture), t)
+}
+
+type ServerLoggerFixture struct {
+	*gunit.Fixture
+
+	mutex  sync.Mutex
+	logged []string
+	counts map[string]int
+}
+
+func (this *ServerLoggerFixture) Setup() {
+	this.counts = make(map[string]int)
+}
+func (this *ServerLoggerFixture) initialize(options ...option) *log.Logger {
+	var config configuration
+	Options.apply(append(options, Options.ErrorLogger(this), Options.Monitor(this))...)(&config)
+	return newServerLogger(config)
+}
+
+func (this *ServerLoggerFixture) TestClassification() {
+	for message, expected := range map[string]ServerLogClass{
+		"http: TLS handshake error from 127.0.0.1:1234: EOF":                           ServerLogTLSHandshake,
+		"http: Accept error: accept tcp [::]:80: too many open files; retrying in 5ms": ServerLogAcceptError,
+		"http: superfluous response.WriteHeader call from main.handler (main.go:12)":   ServerLogSuperfluousWriteHeader,
+		"http: URL query contains semicolon ... see golang.org/issue/25192":            ServerLogQuerySemicolon,
+		"http2: server read frame: request header too large":                           ServerLogHeaderTooLarge,
+		"http: panic serving 127.0.0.1:1234: boink":                                    ServerLogOther,
+	} {
+		this.So(classifyServerLog([]byte(message)), should.Equal, expected)
+	}
+}
+func (this *ServerLoggerFixture) TestDefaultSuppression_OnlyQuerySemicolonSuppressed() {
+	logger := this.initialize()
+
+	logger.Print("http: URL query contains semicolon ... see golang.org/issue/25192")
+	logger.Print("http: TLS handshake error from 127.0.0.1:1234: EOF")
+
+	this.So(this.logged, should.Equal, []string{"[WARN] http: TLS handshake error from 127.0.0.1:1234: EOF"})
+	this.So(this.counts, should.Equal, map[string]int{"query-semicolon true": 1, "tls-handshake false": 1})
+}
+func (this *ServerLoggerFixture) TestConfiguredSuppression() {
+	logger := this.initialize(Options.SuppressServerLog(ServerLogTLSHandshake))
+
+	logger.Print("http: TLS handshake error from 127.0.0.1:1234: EOF")
+	logger.Print("http: URL query contains semicolon ... see golang.org/issue/25192")
+
+	this.So(this.logged, should.BeEmpty)
+	this.So(this.counts, should.Equal, map[string]int{"query-semicolon true": 1, "tls-handshake true": 1})
+}
+func (this *ServerLoggerFixture) TestRateLimitedPerClass_SummaryWrittenOnceIntervalConcludes() {
+	logger := this.initialize(Options.ServerLogRateLimit(2, time.Millisecond*10))
+
+	for range 5 {
+		logger.Print("http: TLS handshake error from 127.0.0.1:1234: EOF")
+	}
+	logger.Print("http: panic serving 127.0.0.1:1234: boink")
+	time.Sleep(time.Millisecond * 20)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.So(this.logged, should.HaveLength, 4)
+	this.So(this.logged[2], should.Equal, "[WARN] http: panic serving 127.0.0.1:1234: boink")
+	this.So(this.logged[3], should.Equal, "[WARN] 3 [tls-handshake] messages from http.Server suppressed in the last 10ms (limit 2)")
+	this.So(this.counts["tls-handshake true"], should.Equal, 3)
+}
+
+func (this *ServerLoggerFixture) Printf(format string, args ...any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.logged = append(this.logged, fmt.Sprintf(format, args...))
+}
+func (this *ServerLoggerFixture) PanicRecovered(*http.Request, any) {}
+func (this *ServerLoggerFixture) ServerLogged(class ServerLogClass, suppressed bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.counts[fmt.Sprint(class, " ", suppressed)]++
+}
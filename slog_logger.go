@@ -0,0 +1,59 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// slogLogger adapts a *slog.Logger such that the server emits structured records with levels and attributes (see
+// logEvent). Anything written through Printf is logged at the level indicated by its "[LEVEL]" prefix, if any.
+type slogLogger struct {
+	inner *slog.Logger
+}
+
+func newSlogLogger(inner *slog.Logger) logger {
+	if inner == nil {
+		return nil
+	}
+	return &slogLogger{inner: inner}
+}
+
+func (this *slogLogger) Printf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	level := slog.LevelInfo
+	for _, item := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if prefix := "[" + item.String() + "] "; strings.HasPrefix(message, prefix) {
+			level, message = item, message[len(prefix):]
+			break
+		}
+	}
+	this.log(level, message)
+}
+func (this *slogLogger) log(level slog.Level, message string, attrs ...slog.Attr) {
+	this.inner.LogAttrs(context.Background(), level, message, attrs...)
+}
+
+func requestAttrs(request *http.Request) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.String("path", request.URL.Path),
+		slog.String("remote_address", request.RemoteAddr),
+	}
+	if id := RequestIDFromContext(request.Context()); len(id) > 0 {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	return attrs
+}
+
+// logEvent writes the message to a structured logger at the level and with the attributes provided, otherwise to the
+// Printf logger prefixed with the level (e.g. "[INFO] ...") as it always has been.
+func logEvent(target logger, level slog.Level, attrs []slog.Attr, format string, args ...any) {
+	if structured, ok := target.(*slogLogger); ok {
+		structured.log(level, fmt.Sprintf(format, args...), attrs...)
+	} else {
+		target.Printf("["+level.String()+"] "+format, args...)
+	}
+}
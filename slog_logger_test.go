@@ -0,0 +1,125 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestSlogLoggerFixture(t *testing.T) {
+	gunit.Run(new(SlogLoggerFixture), t)
+}
+
+type SlogLoggerFixture struct {
+	*gunit.Fixture
+
+	buffer *bytes.Buffer
+	slog   *slog.Logger
+}
+
+func (this *SlogLoggerFixture) Setup() {
+	this.buffer = new(bytes.Buffer)
+	this.slog = slog.New(slog.NewJSONHandler(this.buffer, nil))
+}
+
+func (this *SlogLoggerFixture) TestLogEvent_StructuredRecordWithLevelAndAttributes() {
+	logEvent(newSlogLogger(this.slog), slog.LevelWarn, []slog.Attr{slog.String("address", ":80")}, "Unable to listen on [%s]", ":80")
+
+	record := this.records()[0]
+	this.So(record["level"], should.Equal, "WARN")
+	this.So(record["msg"], should.Equal, "Unable to listen on [:80]")
+	this.So(record["address"], should.Equal, ":80")
+}
+func (this *SlogLoggerFixture) TestLogEvent_PrintfLoggerReceivesLevelPrefix() {
+	var logged []string
+	target := printfFunc(func(format string, args ...any) { logged = append(logged, format) })
+
+	logEvent(target, slog.LevelInfo, []slog.Attr{slog.String("address", ":80")}, "Listening on [%s]", ":80")
+
+	this.So(logged, should.Equal, []string{"[INFO] Listening on [%s]"})
+}
+func (this *SlogLoggerFixture) TestPrintf_LevelTakenFromPrefix() {
+	target := newSlogLogger(this.slog)
+
+	target.Printf("[ERROR] failure %d", 42)
+	target.Printf("no prefix")
+
+	records := this.records()
+	this.So(records[0]["level"], should.Equal, "ERROR")
+	this.So(records[0]["msg"], should.Equal, "failure 42")
+	this.So(records[1]["level"], should.Equal, "INFO")
+}
+func (this *SlogLoggerFixture) TestServerLogger_ForwardedAsWarningWithRemoteAddress() {
+	newServerLogger(configuration{ErrorLogger: newSlogLogger(this.slog), Monitor: &nop{}}).Printf("http: TLS handshake error from 127.0.0.1:1234: EOF")
+
+	record := this.records()[0]
+	this.So(record["level"], should.Equal, "WARN")
+	this.So(record["remote_address"], should.Equal, "127.0.0.1:1234")
+	this.So(record["source"], should.Equal, "http.Server")
+}
+func (this *SlogLoggerFixture) TestRecoveredPanic_StackAndRequestAsAttributes() {
+	var config configuration
+	Options.apply(Options.StructuredLogger(this.slog), Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boink")
+	})))(&config)
+
+	config.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/path", nil))
+
+	record := this.records()[0]
+	this.So(record["level"], should.Equal, "ERROR")
+	this.So(record["msg"], should.Equal, "Recovered panic: boink")
+	this.So(record["method"], should.Equal, "POST")
+	this.So(record["path"], should.Equal, "/path")
+	this.So(record["stack"], should.ContainSubstring, "_test.go:")
+}
+func (this *SlogLoggerFixture) TestNilStructuredLogger_RecoveredPanicDiscarded() {
+	var config configuration
+	Options.apply(Options.StructuredLogger(nil), Options.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boink")
+	})))(&config)
+	recorder := httptest.NewRecorder()
+
+	config.Handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/path", nil))
+	newServerLogger(config).Printf("http: TLS handshake error from 127.0.0.1:1234: EOF")
+
+	this.So(recorder.Code, should.Equal, http.StatusInternalServerError)
+	this.So(Validate(Options.StructuredLogger(nil)), should.BeNil)
+}
+func (this *SlogLoggerFixture) TestStructuredAccessLogger_FieldsAsAttributes() {
+	var config configuration
+	Options.apply(
+		Options.StructuredAccessLogger(this.slog),
+		Options.AccessLogFields(AccessLogFieldTime, AccessLogFieldMethod, AccessLogFieldStatus),
+		Options.AccessLogHeaders("X-Custom"),
+	)(&config)
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Custom", "value")
+
+	config.Handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	record := this.records()[0]
+	this.So(record["msg"], should.Equal, "HTTP request")
+	this.So(record["method"], should.Equal, "GET")
+	this.So(record["status"], should.Equal, 200.0)
+	this.So(record["headers"], should.Equal, map[string]any{"X-Custom": "value"})
+}
+
+func (this *SlogLoggerFixture) records() (records []map[string]any) {
+	for _, line := range strings.Split(strings.TrimSpace(this.buffer.String()), "\n") {
+		var record map[string]any
+		_ = json.Unmarshal([]byte(line), &record)
+		records = append(records, record)
+	}
+	return records
+}
+
+type printfFunc func(string, ...any)
+
+func (this printfFunc) Printf(format string, args ...any) { this(format, args...) }
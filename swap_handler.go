@@ -0,0 +1,55 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type swapHandler struct {
+	mutex   sync.Mutex
+	current atomic.Pointer[handlerGeneration]
+}
+type handlerGeneration struct {
+	http.Handler
+	generation uint64
+}
+
+func newSwapHandler(handler http.Handler) *swapHandler {
+	this := &swapHandler{}
+	this.current.Store(&handlerGeneration{Handler: coalesceHandler(handler), generation: 1})
+	return this
+}
+
+func (this *swapHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	current := this.current.Load() // in-flight requests keep the generation they started with
+	ctx := context.WithValue(request.Context(), handlerGenerationKey{}, current.generation)
+	current.ServeHTTP(response, request.WithContext(ctx))
+}
+
+func (this *swapHandler) Swap(handler http.Handler) uint64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	next := &handlerGeneration{Handler: coalesceHandler(handler), generation: this.current.Load().generation + 1}
+	this.current.Store(next)
+	return next.generation
+}
+
+func coalesceHandler(handler http.Handler) http.Handler {
+	if handler == nil {
+		return &nop{}
+	}
+	return handler
+}
+
+type handlerGenerationKey struct{}
+
+// HandlerGeneration returns the generation of the root handler serving the request associated with the context
+// provided, starting at 1 and incremented each time the handler is swapped; zero indicates the context didn't originate
+// from this server.
+func HandlerGeneration(ctx context.Context) uint64 {
+	generation, _ := ctx.Value(handlerGenerationKey{}).(uint64)
+	return generation
+}
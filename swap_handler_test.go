@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestSwapHandlerFixture(t *testing.T) {
+	gunit.Run(new(SwapHandlerFixture), t)
+}
+
+type SwapHandlerFixture struct {
+	*gunit.Fixture
+
+	handler  *swapHandler
+	response *httptest.ResponseRecorder
+	request  *http.Request
+}
+
+func (this *SwapHandlerFixture) Setup() {
+	this.handler = newSwapHandler(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Generation", "first")
+		response.WriteHeader(generationStatus(request))
+	}))
+	this.response = httptest.NewRecorder()
+	this.request = httptest.NewRequest("GET", "/", nil)
+}
+
+func (this *SwapHandlerFixture) TestInitialHandler_FirstGenerationOnContext() {
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Header().Get("Generation"), should.Equal, "first")
+	this.So(this.response.Code, should.Equal, 201)
+	this.So(HandlerGeneration(this.request.Context()), should.Equal, 0)
+}
+func (this *SwapHandlerFixture) TestSwappedHandler_SubsequentRequestsServedByNewHandlerAndGeneration() {
+	generation := this.handler.Swap(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Generation", "second")
+		response.WriteHeader(generationStatus(request))
+	}))
+
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(generation, should.Equal, 2)
+	this.So(this.response.Header().Get("Generation"), should.Equal, "second")
+	this.So(this.response.Code, should.Equal, 202)
+}
+func (this *SwapHandlerFixture) TestSwapDuringRequest_InFlightRequestCompletesOnOriginalHandler() {
+	started, release := make(chan struct{}), make(chan struct{})
+	this.handler = newSwapHandler(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+		response.Header().Set("Generation", "first")
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		this.handler.ServeHTTP(this.response, this.request)
+	}()
+	<-started
+	this.handler.Swap(http.NotFoundHandler())
+	close(release)
+	<-done
+
+	this.So(this.response.Header().Get("Generation"), should.Equal, "first")
+	this.So(this.response.Code, should.Equal, 200)
+}
+func (this *SwapHandlerFixture) TestSwapNilHandler_NopHandlerInstalled() {
+	this.handler.Swap(nil)
+
+	this.handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 200)
+	this.So(this.response.Header().Get("Generation"), should.BeEmpty)
+}
+func (this *SwapHandlerFixture) TestSwappedHandlerPanics_RecoveryHandlerConfiguredThroughOptionsStillApplies() {
+	server := New(Options.Handler(http.NotFoundHandler())).(*defaultServer)
+	server.SwapHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boink") }))
+
+	server.config.Handler.ServeHTTP(this.response, this.request)
+
+	this.So(this.response.Code, should.Equal, 500)
+}
+
+func generationStatus(request *http.Request) int {
+	return 200 + int(HandlerGeneration(request.Context()))
+}
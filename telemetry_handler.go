@@ -0,0 +1,34 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// telemetryHandler reports each request to the monitor before and after it passes through the rest of the handler
+// pipeline such that the status written by recovery, the connection lifetime and any slow client protection is visible.
+type telemetryHandler struct {
+	http.Handler
+	monitor requestMonitor
+}
+
+func newTelemetryHandler(handler http.Handler, monitor requestMonitor) *telemetryHandler {
+	return &telemetryHandler{Handler: handler, monitor: monitor}
+}
+
+func (this *telemetryHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	started := time.Now()
+	tracked := newTrackingResponseWriter(response)
+	completed := false
+	defer func() {
+		status := tracked.Status()
+		if !completed {
+			status = 0 // panic not recovered, the connection is aborted by net/http
+		}
+		this.monitor.RequestFinished(request, status, tracked.written, time.Since(started))
+	}()
+
+	this.monitor.RequestStarted(request)
+	this.Handler.ServeHTTP(tracked.exposed(), request)
+	completed = true
+}
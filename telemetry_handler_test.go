@@ -0,0 +1,141 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestTelemetryFixture(t *testing.T) {
+	gunit.Run(new(TelemetryFixture), t)
+}
+
+type TelemetryFixture struct {
+	*gunit.Fixture
+
+	mutex     sync.Mutex
+	started   int
+	finished  []int
+	written   []int64
+	states    []http.ConnState
+	handshake []string
+	bound     []string
+	failed    []error
+	phases    []ShutdownPhase
+}
+
+func (this *TelemetryFixture) serve(handler http.HandlerFunc) *httptest.Server {
+	var config configuration
+	Options.apply(Options.Handler(handler), Options.Monitor(this))(&config)
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = config.HTTPServer.(*http.Server)
+	server.Start()
+	return server
+}
+
+func (this *TelemetryFixture) TestRequestFinished_StatusAndBytesReported() {
+	server := this.serve(func(response http.ResponseWriter, _ *http.Request) {
+		response.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(response, "created")
+	})
+	defer server.Close()
+
+	this.get(server)
+	server.Close()
+
+	this.So(this.started, should.Equal, 1)
+	this.So(this.finished, should.Equal, []int{http.StatusCreated})
+	this.So(this.written, should.Equal, []int64{7})
+	this.So(this.states, should.Equal, []http.ConnState{http.StateNew, http.StateActive, http.StateIdle, http.StateClosed})
+}
+func (this *TelemetryFixture) TestImplicitStatus_ReportedAsOK() {
+	server := this.serve(func(http.ResponseWriter, *http.Request) {})
+	defer server.Close()
+
+	this.get(server)
+
+	this.So(this.finished, should.Equal, []int{http.StatusOK})
+}
+func (this *TelemetryFixture) TestRecoveredPanic_ReportedAsInternalServerError() {
+	server := this.serve(func(http.ResponseWriter, *http.Request) { panic("boink") })
+	defer server.Close()
+
+	this.get(server)
+
+	this.So(this.finished, should.Equal, []int{http.StatusInternalServerError})
+}
+func (this *TelemetryFixture) TestTLSHandshakeError_ReportedFromServerLog() {
+	logger := newServerLogger(configuration{ErrorLogger: &nop{}, Monitor: this})
+
+	logger.Printf("http: TLS handshake error from 127.0.0.1:1234: EOF")
+	logger.Printf("http: superfluous response.WriteHeader call")
+
+	this.So(this.handshake, should.Equal, []string{"127.0.0.1:1234", "EOF"})
+}
+func (this *TelemetryFixture) TestListenAndShutdown_BindAndPhasesReported() {
+	ctx, cancel := context.WithCancel(this.T().Context())
+	server := New(Options.Context(ctx), Options.ListenAddress("127.0.0.1:0"), Options.Monitor(this))
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	server.Listen()
+
+	this.So(this.bound, should.Equal, []string{"tcp", "127.0.0.1:0"})
+	this.So(this.phases, should.Equal, []ShutdownPhase{ShutdownStarted, ShutdownDrained, ShutdownCompleted})
+}
+func (this *TelemetryFixture) TestListenFails_FailureReported() {
+	server := New(Options.ListenAddress("127.0.0.1:-1"), Options.Monitor(this))
+	time.AfterFunc(time.Millisecond*50, func() { _ = server.Close() })
+
+	server.Listen()
+
+	this.So(this.bound, should.BeEmpty)
+	this.So(this.failed, should.HaveLength, 1)
+}
+
+func (this *TelemetryFixture) get(server *httptest.Server) {
+	response, err := server.Client().Get(server.URL)
+	if err != nil {
+		this.Error(err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+}
+
+func (this *TelemetryFixture) PanicRecovered(*http.Request, any) {}
+func (this *TelemetryFixture) RequestStarted(*http.Request) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.started++
+}
+func (this *TelemetryFixture) RequestFinished(_ *http.Request, status int, written int64, _ time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.finished = append(this.finished, status)
+	this.written = append(this.written, written)
+}
+func (this *TelemetryFixture) ConnectionStateChanged(_ net.Conn, state http.ConnState) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.states = append(this.states, state)
+}
+func (this *TelemetryFixture) TLSHandshakeFailed(remoteAddress, reason string) {
+	this.handshake = append(this.handshake, remoteAddress, reason)
+}
+func (this *TelemetryFixture) ListenSucceeded(network, address string) {
+	this.bound = append(this.bound, network, address)
+}
+func (this *TelemetryFixture) ListenFailed(_, _ string, err error) {
+	this.failed = append(this.failed, err)
+}
+func (this *TelemetryFixture) ShutdownPhaseEntered(phase ShutdownPhase, _ time.Duration) {
+	this.phases = append(this.phases, phase)
+}
@@ -0,0 +1,295 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// throughputHandler enforces a minimum transfer rate per connection while reading request bodies and writing
+// responses. Rather than an absolute deadline, only the time spent waiting on the peer (blocked in Read or Write) is
+// considered such that time spent by the handler itself, as well as the time a keep-alive connection spends idle between
+// requests (governed by IdleConnectionTimeout instead), is never held against the client. Once a connection has spent
+// at least one window blocked on the peer, across however many requests, the bytes transferred during the time blocked
+// within the most recent window must meet the minimum rate, otherwise the connection is aborted. When enabled, the
+// absolute ReadRequestTimeout and WriteResponseTimeout are lifted for the request body and response respectively.
+type throughputHandler struct {
+	http.Handler
+	minReadRate  int
+	minWriteRate int
+	window       time.Duration
+	monitor      slowClientMonitor
+	logger       logger
+}
+
+func newThroughputHandler(handler http.Handler, minReadRate, minWriteRate int, window time.Duration, monitor monitor, logger logger) *throughputHandler {
+	window = max(window, minThroughputWindow) // New doesn't validate, a zero window would check continuously
+	this := &throughputHandler{Handler: handler, minReadRate: minReadRate, minWriteRate: minWriteRate, window: window, logger: logger}
+	this.monitor, _ = monitor.(slowClientMonitor)
+	return this
+}
+
+func (this *throughputHandler) ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, throughputConnectionKey{}, this.newConnection(conn))
+}
+func (this *throughputHandler) newConnection(conn net.Conn) *throughputConnection {
+	connection := &throughputConnection{conn: conn}
+	connection.read = newThroughputMeter(false, this.minReadRate, this.window, this.abort)
+	connection.write = newThroughputMeter(true, this.minWriteRate, this.window, this.abort)
+	return connection
+}
+
+func (this *throughputHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	connection, ok := request.Context().Value(throughputConnectionKey{}).(*throughputConnection)
+	if !ok {
+		connection = this.newConnection(nil) // not served by the http.Server created by New, only this request is metered
+		defer connection.stop()
+	}
+
+	controller := http.NewResponseController(response)
+	transfer := &throughputTransfer{connection: connection, request: request, controller: controller}
+
+	if this.minReadRate > 0 && request.Body != nil && request.Body != http.NoBody {
+		_ = controller.SetReadDeadline(time.Time{})
+		request.Body = &meteredBody{ReadCloser: request.Body, meter: connection.read, transfer: transfer}
+	}
+
+	if this.minWriteRate > 0 {
+		_ = controller.SetWriteDeadline(time.Time{})
+		response = &meteredResponseWriter{ResponseWriter: response, meter: connection.write, transfer: transfer}
+	}
+
+	this.Handler.ServeHTTP(response, request)
+}
+func (this *throughputHandler) abort(meter *throughputMeter, transfer *throughputTransfer, rate float64) {
+	request := transfer.request
+	direction := "reading request body"
+	if meter.writing {
+		direction = "writing response"
+	}
+	attrs := append(requestAttrs(request), slog.String("direction", direction), slog.Float64("bytes_per_second", rate))
+	logEvent(this.logger, slog.LevelWarn, attrs, "Aborting slow client [%s] %s at %.0f bytes/sec (minimum %d bytes/sec): %s %s",
+		request.RemoteAddr, direction, rate, meter.minRate, request.Method, request.URL.Path)
+
+	if this.monitor != nil {
+		this.monitor.SlowClientAborted(request, meter.writing, rate)
+	}
+
+	transfer.abort()
+}
+
+type throughputConnectionKey struct{}
+
+// throughputConnection holds the meters of a single connection which are shared by all of its requests.
+type throughputConnection struct {
+	conn  net.Conn // nil unless provided by ConnContext
+	read  *throughputMeter
+	write *throughputMeter
+}
+
+func (this *throughputConnection) stop() {
+	this.read.stop()
+	this.write.stop()
+}
+
+// throughputTransfer identifies the request on whose behalf a connection is waiting on the peer.
+type throughputTransfer struct {
+	connection *throughputConnection
+	request    *http.Request
+	controller *http.ResponseController
+}
+
+func (this *throughputTransfer) abort() {
+	now := time.Now()
+	if conn := this.connection.conn; conn != nil {
+		_ = conn.SetDeadline(now) // unblocks any pending I/O of every request on the connection, after which it's closed
+		return
+	}
+	_ = this.controller.SetReadDeadline(now) // unblocks any pending I/O after which net/http closes the connection
+	_ = this.controller.SetWriteDeadline(now)
+}
+
+// throughputMeter measures the rate of a single direction of a connection. Concurrent operations (e.g. HTTP/2 streams)
+// overlap into a single period of waiting on the peer.
+type throughputMeter struct {
+	writing bool
+	minRate int
+	window  time.Duration
+	onSlow  func(*throughputMeter, *throughputTransfer, float64)
+
+	mutex    sync.Mutex
+	samples  []throughputSample
+	blocked  time.Duration // total time blocked on the peer since the connection was established
+	active   int           // number of operations in progress
+	pending  time.Time     // start of the period during which operations are in progress, if any
+	bytes    int           // transferred so far during the pending period
+	transfer *throughputTransfer
+	timer    *time.Timer
+	aborted  bool
+	stopped  bool
+}
+type throughputSample struct {
+	started time.Time
+	ended   time.Time
+	bytes   int
+}
+
+func newThroughputMeter(writing bool, minRate int, window time.Duration, onSlow func(*throughputMeter, *throughputTransfer, float64)) *throughputMeter {
+	return &throughputMeter{writing: writing, minRate: minRate, window: window, onSlow: onSlow}
+}
+
+func (this *throughputMeter) begin(transfer *throughputTransfer, now time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.active == 0 {
+		this.pending = now
+	}
+	this.active++
+	this.transfer = transfer
+	if this.timer == nil && !this.stopped {
+		this.timer = time.AfterFunc(this.interval(), this.check)
+	}
+}
+func (this *throughputMeter) end(bytes int, now time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.bytes += bytes
+	if this.active--; this.active > 0 {
+		return
+	}
+
+	this.blocked += now.Sub(this.pending)
+	this.samples = append(this.prune(now), throughputSample{started: this.pending, ended: now, bytes: this.bytes})
+	transfer := this.transfer
+	this.pending, this.bytes, this.transfer = time.Time{}, 0, nil
+
+	// operations shorter than the interval (e.g. many small requests) would otherwise never be checked
+	if rate, slow := this.measure(now); slow && !this.aborted && !this.stopped {
+		this.aborted = true
+		go this.onSlow(this, transfer, rate)
+	}
+}
+func (this *throughputMeter) check() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.timer = nil
+	if this.stopped || this.aborted || this.active == 0 {
+		return // no longer waiting on the peer, the timer is started again by the next operation
+	}
+
+	if rate, slow := this.measure(time.Now()); slow {
+		this.aborted = true
+		go this.onSlow(this, this.transfer, rate)
+		return
+	}
+
+	this.timer = time.AfterFunc(this.interval(), this.check)
+}
+func (this *throughputMeter) measure(now time.Time) (rate float64, slow bool) {
+	blocked, bytes := time.Duration(0), 0
+	if this.active > 0 {
+		blocked, bytes = now.Sub(this.pending), this.bytes
+	}
+
+	if this.blocked+blocked < this.window {
+		return 0, false // not enough evidence yet
+	}
+
+	since := now.Add(-this.window)
+	if this.active > 0 {
+		blocked = now.Sub(maxTime(this.pending, since))
+	}
+	for _, sample := range this.prune(now) {
+		blocked += sample.ended.Sub(maxTime(sample.started, since))
+		bytes += sample.bytes
+	}
+
+	if blocked <= 0 {
+		return 0, false
+	}
+
+	rate = float64(bytes) / blocked.Seconds()
+	return rate, rate < float64(this.minRate)
+}
+func (this *throughputMeter) prune(now time.Time) []throughputSample {
+	since := now.Add(-this.window)
+	for len(this.samples) > 0 && this.samples[0].ended.Before(since) {
+		this.samples = this.samples[1:]
+	}
+	return this.samples
+}
+func (this *throughputMeter) interval() time.Duration {
+	return this.window / 4
+}
+func (this *throughputMeter) stop() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.stopped = true
+	if this.timer != nil {
+		this.timer.Stop()
+	}
+}
+
+const minThroughputWindow = time.Millisecond * 10
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+type meteredBody struct {
+	io.ReadCloser
+	meter    *throughputMeter
+	transfer *throughputTransfer
+}
+
+func (this *meteredBody) Read(buffer []byte) (int, error) {
+	this.meter.begin(this.transfer, time.Now())
+	n, err := this.ReadCloser.Read(buffer)
+	this.meter.end(n, time.Now())
+	return n, err
+}
+
+type meteredResponseWriter struct {
+	http.ResponseWriter
+	meter    *throughputMeter
+	transfer *throughputTransfer
+}
+
+func (this *meteredResponseWriter) Write(buffer []byte) (written int, err error) {
+	for len(buffer) > 0 && err == nil {
+		chunk := buffer[:min(len(buffer), meteredWriteChunkSize)] // progress within a large write is otherwise invisible
+		this.meter.begin(this.transfer, time.Now())
+		var n int
+		n, err = this.ResponseWriter.Write(chunk)
+		this.meter.end(n, time.Now())
+		written += n
+		buffer = buffer[n:]
+	}
+	return written, err
+}
+func (this *meteredResponseWriter) Flush() {
+	this.meter.begin(this.transfer, time.Now())
+	_ = http.NewResponseController(this.ResponseWriter).Flush()
+	this.meter.end(0, time.Now())
+}
+func (this *meteredResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	this.transfer.connection.stop() // the connection is no longer managed by net/http
+	return http.NewResponseController(this.ResponseWriter).Hijack()
+}
+func (this *meteredResponseWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+
+const meteredWriteChunkSize = 1024 * 16
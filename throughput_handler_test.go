@@ -0,0 +1,241 @@
+package httpserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestThroughputHandlerFixture(t *testing.T) {
+	gunit.Run(new(ThroughputHandlerFixture), t)
+}
+
+type ThroughputHandlerFixture struct {
+	*gunit.Fixture
+
+	server  *httptest.Server
+	handled chan error
+
+	mutex   sync.Mutex
+	aborted []bool
+}
+
+func (this *ThroughputHandlerFixture) Setup() {
+	this.handled = make(chan error, 4)
+}
+func (this *ThroughputHandlerFixture) Teardown() {
+	if this.server != nil {
+		_ = this.server.Config.Close() // unlike httptest.Server.Close, doesn't await net/http lingering on aborted connections
+	}
+}
+func (this *ThroughputHandlerFixture) initialize(handler http.HandlerFunc, options ...option) {
+	var config configuration
+	options = append([]option{Options.MinTransferRateWindow(time.Millisecond * 20)}, append(options, Options.Handler(handler), Options.Monitor(this))...)
+	Options.apply(options...)(&config)
+	this.server = httptest.NewUnstartedServer(nil)
+	this.server.Config = config.HTTPServer.(*http.Server)
+	this.server.Start()
+}
+func (this *ThroughputHandlerFixture) readBody(_ http.ResponseWriter, request *http.Request) {
+	_, err := io.ReadAll(request.Body)
+	this.handled <- err
+}
+
+func (this *ThroughputHandlerFixture) TestSlowRequestBody_ConnectionAbortedAndReported() {
+	this.initialize(this.readBody, Options.MinRequestBodyRate(1024))
+
+	conn := this.dial()
+	defer func() { _ = conn.Close() }()
+	_, _ = io.WriteString(conn, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 100\r\n\r\n")
+	go this.trickle(conn, 100, time.Millisecond*2)
+
+	this.So(this.result(), should.NotBeNil)
+	this.So(this.abortedRequests(), should.Equal, []bool{false})
+}
+func (this *ThroughputHandlerFixture) LongTestSlowRequestBodiesOnSameConnection_ConnectionAbortedOnceWindowExceeded() {
+	this.initialize(this.readBody, Options.MinRequestBodyRate(1024), Options.MinTransferRateWindow(time.Millisecond*100))
+
+	conn := this.dial()
+	defer func() { _ = conn.Close() }()
+	go func() {
+		for range 6 { // each request spends a fraction of the window blocked on the client, together they exceed it
+			_, _ = io.WriteString(conn, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 6\r\n\r\n")
+			if !this.trickle(conn, 6, time.Millisecond*4) {
+				return
+			}
+		}
+	}()
+
+	this.So(this.result(), should.BeNil)
+	this.So(this.result(), should.BeNil)
+	for err := this.result(); err == nil && !this.Failed(); err = this.result() {
+	}
+	this.So(this.abortedRequests(), should.Equal, []bool{false})
+}
+func (this *ThroughputHandlerFixture) TestFastRequestBodyExceedingReadTimeout_NotAborted() {
+	this.initialize(this.readBody, Options.MinRequestBodyRate(1024), Options.ReadRequestTimeout(time.Millisecond*10))
+
+	conn := this.dial()
+	defer func() { _ = conn.Close() }()
+	_, _ = io.WriteString(conn, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 20480\r\n\r\n")
+	go func() {
+		for range 5 {
+			_, _ = conn.Write(make([]byte, 4096))
+			time.Sleep(time.Millisecond * 4)
+		}
+	}()
+
+	this.So(this.result(), should.BeNil)
+	this.So(this.abortedRequests(), should.BeEmpty)
+}
+func (this *ThroughputHandlerFixture) TestHandlerProcessingTime_NotCountedAgainstClient() {
+	this.initialize(func(response http.ResponseWriter, request *http.Request) {
+		time.Sleep(time.Millisecond * 30) // three windows
+		_, _ = io.WriteString(response, "complete")
+	}, Options.MinResponseRate(1024), Options.MinTransferRateWindow(time.Millisecond*10))
+
+	response, err := this.server.Client().Get(this.server.URL)
+	if err != nil {
+		this.Error(err)
+		return
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	this.So(string(body), should.Equal, "complete")
+	this.So(this.abortedRequests(), should.BeEmpty)
+}
+func (this *ThroughputHandlerFixture) TestSlowResponseReader_ConnectionAbortedAndReported() {
+	this.initialize(func(response http.ResponseWriter, _ *http.Request) {
+		var err error
+		chunk := make([]byte, 1024*64)
+		for written := 0; err == nil && written < 1024*1024*512; written += len(chunk) {
+			_, err = response.Write(chunk)
+		}
+		this.handled <- err
+	}, Options.MinResponseRate(1024*1024))
+
+	conn := this.dial()
+	defer func() { _ = conn.Close() }()
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	_, _ = http.ReadResponse(bufio.NewReader(conn), nil) // headers only, the body is never read
+
+	this.So(this.result(), should.NotBeNil)
+	this.So(this.abortedRequests(), should.Equal, []bool{true})
+}
+func (this *ThroughputHandlerFixture) TestMeteredResponseWriter_FlushAndUnwrapPreserved() {
+	recorder := httptest.NewRecorder()
+	meter := newThroughputMeter(true, 1, time.Minute, nil)
+	defer meter.stop()
+	writer := &meteredResponseWriter{ResponseWriter: recorder, meter: meter}
+
+	_, _ = fmt.Fprint(writer, strings.Repeat("a", meteredWriteChunkSize*2+1))
+	writer.Flush()
+
+	this.So(recorder.Body.Len(), should.Equal, meteredWriteChunkSize*2+1)
+	this.So(recorder.Flushed, should.BeTrue)
+	this.So(writer.Unwrap() == recorder, should.BeTrue)
+}
+
+func (this *ThroughputHandlerFixture) TestZeroWindowWithoutValidation_WindowRaisedToMinimum() {
+	handler := newThroughputHandler(nil, 1024, 0, 0, this, nil)
+
+	this.So(handler.window, should.Equal, minThroughputWindow)
+}
+func (this *ThroughputHandlerFixture) TestNeverBlocked_NoRateMeasured() {
+	meter := newThroughputMeter(false, 1024, 0, nil)
+	now := time.Now()
+	meter.begin(nil, now)
+	defer meter.stop()
+
+	rate, slow := meter.measure(now)
+
+	this.So(rate, should.Equal, 0.0)
+	this.So(slow, should.BeFalse)
+}
+func (this *ThroughputHandlerFixture) TestBlockedAcrossRequests_EvidenceAccumulatesPerConnection() {
+	meter := newThroughputMeter(false, 1024, time.Millisecond*100, nil)
+	defer meter.stop()
+	started := time.Now()
+
+	meter.begin(nil, started)
+	meter.end(10, started.Add(time.Millisecond*60))
+	meter.begin(nil, started.Add(time.Second))
+	rate, slow := meter.measure(started.Add(time.Second + time.Millisecond*50))
+
+	this.So(rate, should.Equal, 0.0)
+	this.So(slow, should.BeTrue)
+}
+func (this *ThroughputHandlerFixture) TestIdleBetweenRequests_NotCountedAgainstClient() {
+	meter := newThroughputMeter(false, 1024, time.Millisecond*100, nil)
+	defer meter.stop()
+	started := time.Now()
+
+	meter.begin(nil, started)
+	meter.end(10, started.Add(time.Millisecond*60))
+	meter.begin(nil, started.Add(time.Second*10))
+	_, slow := meter.measure(started.Add(time.Second*10 + time.Millisecond*30))
+
+	this.So(slow, should.BeFalse)
+}
+func (this *ThroughputHandlerFixture) TestConcurrentOperations_BlockedTimeOverlaps() {
+	meter := newThroughputMeter(false, 1024, time.Millisecond*100, nil)
+	defer meter.stop()
+	started := time.Now()
+
+	meter.begin(nil, started)
+	meter.begin(nil, started.Add(time.Millisecond*10))
+	meter.end(100, started.Add(time.Millisecond*50))
+	meter.end(100, started.Add(time.Millisecond*100))
+
+	this.So(meter.blocked, should.Equal, time.Millisecond*100)
+	this.So(meter.samples, should.Equal, []throughputSample{{started: started, ended: started.Add(time.Millisecond * 100), bytes: 200}})
+}
+
+func (this *ThroughputHandlerFixture) dial() net.Conn {
+	conn, err := net.Dial("tcp", this.server.Listener.Addr().String())
+	if err != nil {
+		this.Error(err)
+	}
+	return conn
+}
+func (this *ThroughputHandlerFixture) trickle(conn net.Conn, bytes int, interval time.Duration) bool {
+	for range bytes {
+		if _, err := conn.Write([]byte("a")); err != nil {
+			return false
+		}
+		time.Sleep(interval)
+	}
+	return true
+}
+func (this *ThroughputHandlerFixture) result() error {
+	select {
+	case err := <-this.handled:
+		return err
+	case <-time.After(time.Second * 5):
+		this.Error("handler did not complete")
+		return nil
+	}
+}
+func (this *ThroughputHandlerFixture) abortedRequests() []bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.aborted
+}
+
+func (this *ThroughputHandlerFixture) PanicRecovered(*http.Request, any) {}
+func (this *ThroughputHandlerFixture) SlowClientAborted(_ *http.Request, writing bool, _ float64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.aborted = append(this.aborted, writing)
+}
@@ -0,0 +1,266 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InMemoryExporter retains every span exported, which is primarily useful for testing.
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (this *InMemoryExporter) ExportSpan(span *Span) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.spans = append(this.spans, span)
+}
+
+// Spans returns the spans exported so far, in the order exported.
+func (this *InMemoryExporter) Spans() []*Span {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return slices.Clone(this.spans)
+}
+func (this *InMemoryExporter) Reset() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.spans = nil
+}
+
+// OTLPExporter sends spans in batches to an OpenTelemetry collector using OTLP over HTTP with JSON encoding. A batch is
+// sent once it's full or once the interval elapses after the first span of the batch was exported, whichever happens
+// first, by a single goroutine such that a slow collector doesn't accumulate goroutines. While the collector is slow or
+// unavailable at most maxPending spans are retained, beyond which the oldest spans are dropped (see Dropped). The server
+// flushes any pending spans once shutdown completes.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	batchSize   int
+	maxPending  int
+	interval    time.Duration
+
+	mutex    sync.Mutex
+	pending  []*Span
+	timer    *time.Timer
+	flushing bool
+	dropped  uint64
+	err      error
+}
+
+// NewOTLPExporter creates an exporter sending to the endpoint of a collector (e.g. "http://localhost:4318/v1/traces")
+// identifying the spans as originating from the service named.
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: time.Second * 10},
+		batchSize:   512,
+		maxPending:  512 * 8,
+		interval:    time.Second * 5,
+	}
+}
+
+func (this *OTLPExporter) ExportSpan(span *Span) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if len(this.pending) >= this.maxPending {
+		this.pending = this.pending[1:]
+		this.dropped++
+	}
+
+	this.pending = append(this.pending, span)
+	if len(this.pending) >= this.batchSize {
+		this.startFlushing()
+	} else if this.timer == nil {
+		this.timer = time.AfterFunc(this.interval, this.flushTimerElapsed)
+	}
+}
+func (this *OTLPExporter) flushTimerElapsed() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.startFlushing()
+}
+
+// startFlushing sends the pending spans in the background unless already sending, in which case the spans are sent
+// once the batch in flight completes. The mutex must be held.
+func (this *OTLPExporter) startFlushing() {
+	if !this.flushing {
+		this.flushing = true
+		go this.flushInBackground()
+	}
+}
+func (this *OTLPExporter) flushInBackground() {
+	for {
+		err := this.Flush(context.Background())
+
+		this.mutex.Lock()
+		this.err = err
+		if len(this.pending) < this.batchSize {
+			this.flushing = false
+			if len(this.pending) > 0 && this.timer == nil {
+				this.timer = time.AfterFunc(this.interval, this.flushTimerElapsed) // spans exported while sending
+			}
+			this.mutex.Unlock()
+			return
+		}
+		this.mutex.Unlock()
+	}
+}
+
+// Err returns the error of the most recent batch sent in the background, if any.
+func (this *OTLPExporter) Err() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.err
+}
+
+// Dropped returns the number of spans dropped because maxPending spans were already waiting to be sent.
+func (this *OTLPExporter) Dropped() uint64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.dropped
+}
+
+// Flush sends any pending spans to the collector in batches of at most batchSize spans. Once a batch fails, the spans
+// remaining are discarded.
+func (this *OTLPExporter) Flush(ctx context.Context) error {
+	this.mutex.Lock()
+	pending := this.pending
+	this.pending = nil
+	if this.timer != nil {
+		this.timer.Stop()
+		this.timer = nil
+	}
+	this.mutex.Unlock()
+
+	for batch := range slices.Chunk(pending, this.batchSize) {
+		if err := this.send(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (this *OTLPExporter) send(ctx context.Context, batch []*Span) error {
+	body, err := json.Marshal(this.encode(batch))
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, this.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := this.client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unable to export %d span(s) to [%s]: %s", len(batch), this.endpoint, response.Status)
+	}
+
+	return nil
+}
+
+func (this *OTLPExporter) encode(batch []*Span) map[string]any {
+	spans := make([]map[string]any, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, encodeOTLPSpan(span))
+	}
+
+	return map[string]any{"resourceSpans": []any{map[string]any{
+		"resource": map[string]any{"attributes": encodeOTLPAttributes(map[string]any{"service.name": this.serviceName})},
+		"scopeSpans": []any{map[string]any{
+			"scope": map[string]any{"name": otlpScopeName},
+			"spans": spans,
+		}},
+	}}}
+}
+func encodeOTLPSpan(span *Span) map[string]any {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	encoded := map[string]any{
+		"traceId":           span.TraceID.String(),
+		"spanId":            span.SpanID.String(),
+		"name":              span.Name,
+		"kind":              otlpSpanKindServer,
+		"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+		"attributes":        encodeOTLPAttributes(span.Attributes),
+		"status":            map[string]any{"code": otlpStatusUnset},
+	}
+
+	if !span.ParentSpanID.IsZero() {
+		encoded["parentSpanId"] = span.ParentSpanID.String()
+	}
+	if len(span.TraceState) > 0 {
+		encoded["traceState"] = span.TraceState
+	}
+	if span.Failed {
+		encoded["status"] = map[string]any{"code": otlpStatusError, "message": span.Error}
+	}
+
+	if len(span.Events) > 0 {
+		events := make([]map[string]any, 0, len(span.Events))
+		for _, event := range span.Events {
+			events = append(events, map[string]any{
+				"name":         event.Name,
+				"timeUnixNano": strconv.FormatInt(event.Time.UnixNano(), 10),
+				"attributes":   encodeOTLPAttributes(event.Attributes),
+			})
+		}
+		encoded["events"] = events
+	}
+
+	return encoded
+}
+func encodeOTLPAttributes(attributes map[string]any) []map[string]any {
+	encoded := make([]map[string]any, 0, len(attributes))
+	for _, key := range sortedKeys(attributes, strings.Compare) {
+		encoded = append(encoded, map[string]any{"key": key, "value": encodeOTLPValue(attributes[key])})
+	}
+	return encoded
+}
+func encodeOTLPValue(value any) map[string]any {
+	switch typed := value.(type) {
+	case string:
+		return map[string]any{"stringValue": typed}
+	case bool:
+		return map[string]any{"boolValue": typed}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(typed), 10)} // 64-bit integers are encoded as strings
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(typed, 10)}
+	case float64:
+		return map[string]any{"doubleValue": typed}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(typed)}
+	}
+}
+
+const (
+	otlpScopeName      = "github.com/smarty/httpserver"
+	otlpSpanKindServer = 2
+	otlpStatusUnset    = 0
+	otlpStatusError    = 2
+)
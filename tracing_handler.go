@@ -0,0 +1,239 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID is the 16 byte identifier of a trace shared by all of its spans (see https://www.w3.org/TR/trace-context/).
+type TraceID [16]byte
+
+// SpanID is the 8 byte identifier of a span within a trace.
+type SpanID [8]byte
+
+func (this TraceID) String() string { return hex.EncodeToString(this[:]) }
+func (this SpanID) String() string  { return hex.EncodeToString(this[:]) }
+func (this TraceID) IsZero() bool   { return this == TraceID{} }
+func (this SpanID) IsZero() bool    { return this == SpanID{} }
+
+// Span is the server span created for each request when a TraceExporter is configured. It's available to the handler
+// through SpanFromContext and is delivered to the exporter once the response has been written.
+type Span struct {
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID // zero when the request didn't carry a valid traceparent header
+	Sampled      bool
+	TraceState   string
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]any
+	Events       []SpanEvent
+	Failed       bool
+	Error        string
+
+	mutex sync.Mutex
+}
+
+// SpanEvent is an annotation recorded at a point in time during a span, e.g. an "exception".
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]any
+}
+
+// SetAttribute adds or replaces an attribute of the span.
+func (this *Span) SetAttribute(key string, value any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.Attributes[key] = value
+}
+
+// AddEvent records an event having the attributes provided at the current time.
+func (this *Span) AddEvent(name string, attributes map[string]any) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.Events = append(this.Events, SpanEvent{Name: name, Time: time.Now(), Attributes: attributes})
+}
+
+// SetError marks the span as failed with the description provided.
+func (this *Span) SetError(description string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.Failed, this.Error = true, description
+}
+
+// SpanFromContext returns the server span of the request, or nil when tracing isn't configured.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// InjectTraceContext writes the traceparent and tracestate headers identifying the server span of the context provided
+// as the parent, such that outgoing requests made while handling a request continue the same trace.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	header.Set(traceParentHeader, formatTraceParent(span.TraceID, span.SpanID, span.Sampled))
+	if len(span.TraceState) > 0 {
+		header.Set(traceStateHeader, span.TraceState)
+	} else {
+		header.Del(traceStateHeader)
+	}
+}
+
+// tracingHandler continues the trace identified by the traceparent header of each request (or starts a new trace,
+// sampled at the TraceSampleRate) with a server span placed on the request context. Sampled spans are delivered to the
+// exporter once complete.
+type tracingHandler struct {
+	http.Handler
+	exporter   SpanExporter
+	sampleRate float64
+	queryNames atomic.Pointer[[]string] // see DumpRedactQuery, lower case
+}
+
+func newTracingHandler(handler http.Handler, config configuration) *tracingHandler {
+	this := &tracingHandler{Handler: handler, exporter: config.TraceExporter, sampleRate: config.TraceSampleRate}
+	this.reload(config)
+	return this
+}
+
+func (this *tracingHandler) reload(config configuration) {
+	queryNames := lowerCase(config.DumpRedactQuery)
+	this.queryNames.Store(&queryNames)
+}
+
+func (this *tracingHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	span := this.newServerSpan(request)
+	tracked := newTrackingResponseWriter(response)
+	defer this.finish(span, tracked)
+
+	this.Handler.ServeHTTP(tracked.exposed(), request.WithContext(context.WithValue(request.Context(), spanKey{}, span)))
+}
+func (this *tracingHandler) finish(span *Span, response *trackingResponseWriter) {
+	span.mutex.Lock()
+	span.End = time.Now()
+	if status := response.Status(); status > 0 {
+		span.Attributes["http.response.status_code"] = status
+		if status >= http.StatusInternalServerError && !span.Failed {
+			span.Failed, span.Error = true, http.StatusText(status)
+		}
+	}
+	span.Attributes["http.response.body.size"] = response.written
+	span.mutex.Unlock()
+
+	if span.Sampled {
+		this.exporter.ExportSpan(span)
+	}
+}
+
+func (this *tracingHandler) newServerSpan(request *http.Request) *Span {
+	span := &Span{
+		Name:  request.Method,
+		Start: time.Now(),
+		Attributes: map[string]any{
+			"http.request.method":      request.Method,
+			"url.path":                 request.URL.Path,
+			"url.scheme":               requestScheme(request),
+			"server.address":           request.Host,
+			"client.address":           request.RemoteAddr,
+			"network.protocol.version": strings.TrimPrefix(request.Proto, "HTTP/"),
+			"user_agent.original":      request.UserAgent(),
+		},
+	}
+
+	if traceID, parentID, sampled, ok := parseTraceParent(request.Header.Get(traceParentHeader)); ok {
+		span.TraceID, span.ParentSpanID, span.Sampled = traceID, parentID, sampled
+		span.TraceState = parseTraceState(request.Header.Values(traceStateHeader))
+	} else {
+		_, _ = rand.Read(span.TraceID[:])
+		span.Sampled = this.sampleRate >= 1 || mathrand.Float64() < this.sampleRate
+	}
+	_, _ = rand.Read(span.SpanID[:])
+
+	if len(request.URL.RawQuery) > 0 {
+		span.Attributes["url.query"] = redactPairs(request.URL.RawQuery, *this.queryNames.Load())
+	}
+
+	return span
+}
+func requestScheme(request *http.Request) string {
+	if request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// parseTraceParent parses a traceparent header, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceParent(value string) (traceID TraceID, parentID SpanID, sampled bool, ok bool) {
+	fields := strings.Split(strings.TrimSpace(value), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" || (fields[0] == "00" && len(fields) != 4) {
+		return traceID, parentID, false, false // later versions may append fields
+	}
+
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], fields[0]) || !decodeLowerHex(traceID[:], fields[1]) ||
+		!decodeLowerHex(parentID[:], fields[2]) || !decodeLowerHex(flags[:], fields[3]) {
+		return traceID, parentID, false, false
+	}
+
+	if traceID.IsZero() || parentID.IsZero() {
+		return traceID, parentID, false, false
+	}
+
+	return traceID, parentID, flags[0]&traceFlagSampled != 0, true
+}
+func decodeLowerHex(target []byte, value string) bool {
+	if len(value) != len(target)*2 || strings.ToLower(value) != value {
+		return false
+	}
+	_, err := hex.Decode(target, []byte(value))
+	return err == nil
+}
+
+// parseTraceState combines the tracestate headers provided, discarding the state entirely if it has more members than
+// allowed or any member is malformed.
+func parseTraceState(values []string) string {
+	var members []string
+	for _, value := range values {
+		for _, member := range strings.Split(value, ",") {
+			if member = strings.TrimSpace(member); len(member) == 0 {
+				continue
+			} else if key, _, found := strings.Cut(member, "="); !found || len(key) == 0 || strings.ContainsAny(key, " \t") {
+				return ""
+			}
+			members = append(members, member)
+		}
+	}
+
+	if len(members) > maxTraceStateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+func formatTraceParent(traceID TraceID, spanID SpanID, sampled bool) string {
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	return "00-" + traceID.String() + "-" + spanID.String() + "-" + flags
+}
+
+type spanKey struct{}
+
+const (
+	traceParentHeader    = "traceparent"
+	traceStateHeader     = "tracestate"
+	traceFlagSampled     = 0x01
+	maxTraceStateMembers = 32
+)
@@ -0,0 +1,253 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestTracingHandlerFixture(t *testing.T) {
+	gunit.Run(new(TracingHandlerFixture), t)
+}
+
+type TracingHandlerFixture struct {
+	*gunit.Fixture
+
+	exporter *InMemoryExporter
+	handler  http.Handler
+	span     *Span
+	outgoing http.Header
+}
+
+func (this *TracingHandlerFixture) Setup() {
+	this.exporter = NewInMemoryExporter()
+	var config configuration
+	Options.apply(Options.TraceExporter(this.exporter), Options.Handler(this))(&config)
+	this.handler = config.Handler
+}
+func (this *TracingHandlerFixture) serve(target string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", target, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	this.handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (this *TracingHandlerFixture) TestNoTraceParent_NewTraceStartedAndExported() {
+	this.serve("/path?query=value", nil)
+
+	spans := this.exporter.Spans()
+	this.So(spans, should.HaveLength, 1)
+	this.So(spans[0], should.Equal, this.span)
+	this.So(spans[0].TraceID.IsZero(), should.BeFalse)
+	this.So(spans[0].ParentSpanID.IsZero(), should.BeTrue)
+	this.So(spans[0].Name, should.Equal, "GET")
+	this.So(spans[0].Attributes["url.path"], should.Equal, "/path")
+	this.So(spans[0].Attributes["url.query"], should.Equal, "query=value")
+	this.So(spans[0].Attributes["http.response.status_code"], should.Equal, 200)
+	this.So(spans[0].Failed, should.BeFalse)
+}
+func (this *TracingHandlerFixture) TestValidTraceParent_TraceContinuedAndPropagated() {
+	this.serve("/", http.Header{
+		"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"Tracestate":  {"vendor1=value1", "vendor2=value2"},
+	})
+
+	span := this.exporter.Spans()[0]
+	this.So(span.TraceID.String(), should.Equal, "4bf92f3577b34da6a3ce929d0e0e4736")
+	this.So(span.ParentSpanID.String(), should.Equal, "00f067aa0ba902b7")
+	this.So(span.TraceState, should.Equal, "vendor1=value1,vendor2=value2")
+	this.So(this.outgoing.Get("traceparent"), should.Equal, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanID.String()+"-01")
+	this.So(this.outgoing.Get("tracestate"), should.Equal, "vendor1=value1,vendor2=value2")
+}
+func (this *TracingHandlerFixture) TestUnsampledTraceParent_NotExported() {
+	this.serve("/", http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}})
+
+	this.So(this.exporter.Spans(), should.BeEmpty)
+	this.So(this.outgoing.Get("traceparent"), should.EndWith, "-00")
+}
+func (this *TracingHandlerFixture) TestSampleRateZero_NewTracesNotExportedButSampledParentsFollowed() {
+	var config configuration
+	Options.apply(Options.TraceExporter(this.exporter), Options.TraceSampleRate(0), Options.Handler(this))(&config)
+	this.handler = config.Handler
+
+	this.serve("/", nil)
+	this.So(this.exporter.Spans(), should.BeEmpty)
+	this.So(this.outgoing.Get("traceparent"), should.EndWith, "-00")
+
+	this.serve("/", http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	this.So(this.exporter.Spans(), should.HaveLength, 1)
+}
+func (this *TracingHandlerFixture) TestRedactedQuery_MaskedInSpan() {
+	var config configuration
+	Options.apply(Options.TraceExporter(this.exporter), Options.DumpRedactQuery("token"), Options.Handler(this))(&config)
+	this.handler = config.Handler
+
+	this.serve("/path?id=1&token=secret", nil)
+
+	this.So(this.exporter.Spans()[0].Attributes["url.query"], should.Equal, "id=1&token=[REDACTED]")
+}
+func (this *TracingHandlerFixture) TestInvalidTraceParent_Ignored() {
+	for _, value := range []string{
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // zero trace
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", // zero parent
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", // uppercase
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", // invalid version
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"garbage",
+	} {
+		_, _, _, ok := parseTraceParent(value)
+		this.So(ok, should.BeFalse)
+	}
+
+	traceID, _, sampled, ok := parseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	this.So(ok, should.BeTrue)
+	this.So(sampled, should.BeTrue)
+	this.So(traceID.String(), should.Equal, "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+func (this *TracingHandlerFixture) TestPanicRecovered_RecordedAsSpanError() {
+	recorder := this.serve("/panic", nil)
+
+	span := this.exporter.Spans()[0]
+	this.So(recorder.Code, should.Equal, 500)
+	this.So(span.Failed, should.BeTrue)
+	this.So(span.Error, should.Equal, "panic: boink")
+	this.So(span.Events, should.HaveLength, 1)
+	this.So(span.Events[0].Name, should.Equal, "exception")
+	this.So(span.Events[0].Attributes["exception.message"], should.Equal, "boink")
+	this.So(span.Attributes["http.response.status_code"], should.Equal, 500)
+}
+func (this *TracingHandlerFixture) TestOTLPExporter_BatchPostedAsJSON() {
+	var received map[string]any
+	var contentType string
+	collector := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		contentType = request.Header.Get("Content-Type")
+		raw, _ := io.ReadAll(request.Body)
+		_ = json.Unmarshal(raw, &received)
+	}))
+	defer collector.Close()
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "my-service")
+	this.serve("/panic", http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	exporter.ExportSpan(this.exporter.Spans()[0])
+
+	err := exporter.Flush(context.Background())
+
+	this.So(err, should.BeNil)
+	this.So(contentType, should.Equal, "application/json")
+	resource := received["resourceSpans"].([]any)[0].(map[string]any)
+	this.So(resource["resource"], should.Equal, map[string]any{"attributes": []any{
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "my-service"}},
+	}})
+	span := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	this.So(span["traceId"], should.Equal, "4bf92f3577b34da6a3ce929d0e0e4736")
+	this.So(span["parentSpanId"], should.Equal, "00f067aa0ba902b7")
+	this.So(span["kind"], should.Equal, 2.0)
+	this.So(span["status"], should.Equal, map[string]any{"code": 2.0, "message": "panic: boink"})
+	this.So(span["events"].([]any)[0].(map[string]any)["name"], should.Equal, "exception")
+	this.So(span["attributes"], should.Contain, map[string]any{"key": "http.response.status_code", "value": map[string]any{"intValue": "500"}})
+}
+func (this *TracingHandlerFixture) TestOTLPExporter_CollectorFailureReported() {
+	collector := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+	exporter := NewOTLPExporter(collector.URL, "my-service")
+	this.serve("/", nil)
+	exporter.ExportSpan(this.exporter.Spans()[0])
+
+	this.So(exporter.Flush(context.Background()), should.NotBeNil)
+	this.So(exporter.Flush(context.Background()), should.BeNil) // nothing pending
+}
+
+func (this *TracingHandlerFixture) TestOTLPExporter_FullBatchesSentOneAtATime() {
+	var mutex sync.Mutex
+	var concurrent, maximum, received int
+	release := make(chan struct{})
+	collector := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		concurrent++
+		maximum = max(maximum, concurrent)
+		mutex.Unlock()
+		<-release
+		var decoded map[string]any
+		raw, _ := io.ReadAll(request.Body)
+		_ = json.Unmarshal(raw, &decoded)
+		mutex.Lock()
+		concurrent--
+		received += len(decoded["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any))
+		mutex.Unlock()
+	}))
+	defer collector.Close()
+	exporter := NewOTLPExporter(collector.URL, "my-service")
+	exporter.batchSize = 1
+	this.serve("/", nil)
+
+	for range 10 {
+		exporter.ExportSpan(this.exporter.Spans()[0])
+	}
+	close(release)
+	for exporter.busy() {
+		time.Sleep(time.Millisecond)
+	}
+
+	this.So(maximum, should.Equal, 1)
+	this.So(received, should.Equal, 10)
+}
+func (this *TracingHandlerFixture) TestOTLPExporter_OldestSpansDroppedBeyondMaximumAndFlushedInBatches() {
+	var mutex sync.Mutex
+	var batches []int
+	collector := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		var decoded map[string]any
+		raw, _ := io.ReadAll(request.Body)
+		_ = json.Unmarshal(raw, &decoded)
+		mutex.Lock()
+		defer mutex.Unlock()
+		batches = append(batches, len(decoded["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)))
+	}))
+	defer collector.Close()
+	exporter := NewOTLPExporter(collector.URL, "my-service")
+	exporter.batchSize, exporter.maxPending = 2, 5
+	exporter.flushing = true // as if a batch is in flight to a slow collector
+	for range 3 {
+		this.serve("/", nil)
+	}
+	spans := this.exporter.Spans()
+
+	for range 3 {
+		for _, span := range spans {
+			exporter.ExportSpan(span)
+		}
+	}
+	pending := append([]*Span(nil), exporter.pending...)
+	err := exporter.Flush(this.T().Context())
+
+	this.So(err, should.BeNil)
+	this.So(exporter.Dropped(), should.Equal, uint64(4))
+	this.So(pending, should.HaveLength, 5)
+	this.So(pending[0] == spans[1], should.BeTrue)
+	this.So(batches, should.Equal, []int{2, 2, 1})
+}
+
+func (this *OTLPExporter) busy() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.flushing || len(this.pending) > 0
+}
+func (this *TracingHandlerFixture) ServeHTTP(_ http.ResponseWriter, request *http.Request) {
+	this.span = SpanFromContext(request.Context())
+	this.outgoing = make(http.Header)
+	InjectTraceContext(request.Context(), this.outgoing)
+	if request.URL.Path == "/panic" {
+		panic("boink")
+	}
+}
@@ -0,0 +1,300 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NewValidated behaves like New but first validates the options provided, returning an error describing every invalid
+// or conflicting setting rather than a server.
+func NewValidated(options ...option) (ListenCloser, error) {
+	if err := Validate(options...); err != nil {
+		return nil, err
+	}
+
+	return New(options...), nil
+}
+
+// Validate returns an error describing every invalid or conflicting setting among the options provided (combined with
+// the defaults used by New), including settings which have no effect because a custom HTTPServer was provided. Each
+// error wraps either ErrInvalidSetting or ErrConflictingSetting.
+func Validate(options ...option) error {
+	var config configuration
+	for _, item := range Options.defaults(options...) {
+		item(&config)
+	}
+
+	return errors.Join(append(config.validate(), config.validateIgnored(optionNames(options...))...)...)
+}
+
+func (this configuration) validate() (errs []error) {
+	if this.Context == nil {
+		errs = append(errs, fmt.Errorf("%w: Context is required", ErrInvalidSetting))
+	}
+
+	errs = append(errs, this.validateListenAddress()...)
+	errs = append(errs, this.validateHTTPServer()...)
+
+	errs = append(errs, this.validateConnectionLimits()...)
+	errs = append(errs, this.validateAccessLog()...)
+	errs = append(errs, this.validateServerLog()...)
+	errs = append(errs, this.validatePanicStorm()...)
+	errs = append(errs, this.validateRequestID()...)
+
+	if this.TraceSampleRate < 0 || this.TraceSampleRate > 1 {
+		errs = append(errs, fmt.Errorf("%w: TraceSampleRate (%g) must be between 0 and 1", ErrInvalidSetting, this.TraceSampleRate))
+	}
+
+	for _, item := range []namedSetting{
+		{name: "ShutdownTimeout", value: int64(this.ShutdownTimeout)},
+		{name: "ForceShutdownTimeout", value: int64(this.ForceShutdownTimeout)},
+	} {
+		errs = append(errs, item.validateNotNegative()...)
+	}
+
+	errs = append(errs, this.validateRequestDump()...)
+
+	if this.ForceShutdownTimeout > this.ShutdownTimeout {
+		errs = append(errs, fmt.Errorf("%w: ForceShutdownTimeout (%s) exceeds ShutdownTimeout (%s); it is the additional time allowed for in-flight requests once the graceful ShutdownTimeout has elapsed",
+			ErrConflictingSetting, this.ForceShutdownTimeout, this.ShutdownTimeout))
+	}
+
+	if this.TLSConfig != nil && len(this.TLSConfig.Certificates) == 0 && this.TLSConfig.GetCertificate == nil && this.TLSConfig.GetConfigForClient == nil {
+		errs = append(errs, fmt.Errorf("%w: TLSConfig has no Certificates, GetCertificate or GetConfigForClient", ErrInvalidSetting))
+	}
+
+	return errs
+}
+func (this configuration) validateListenAddress() (errs []error) {
+	if this.ListenAddressError != nil {
+		return []error{this.ListenAddressError}
+	} else if len(this.ListenAddress) == 0 {
+		return nil // listening is disabled, only an empty ListenAddress parses without an address
+	}
+
+	switch this.ListenNetwork {
+	case "tcp", "tcp4", "tcp6":
+		if _, port, err := net.SplitHostPort(this.ListenAddress); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ListenAddress [%s://%s]: %w", ErrInvalidSetting, this.ListenNetwork, this.ListenAddress, err))
+		} else if _, err = net.LookupPort(this.ListenNetwork, port); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ListenAddress [%s://%s]: %w", ErrInvalidSetting, this.ListenNetwork, this.ListenAddress, err))
+		}
+	case "unix", "unixpacket":
+		if strings.TrimSpace(this.ListenAddress) != this.ListenAddress {
+			errs = append(errs, fmt.Errorf("%w: ListenAddress [%s://%s]: socket path has leading or trailing whitespace", ErrInvalidSetting, this.ListenNetwork, this.ListenAddress))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: ListenAddress [%s://%s]: unsupported network [%s]", ErrInvalidSetting, this.ListenNetwork, this.ListenAddress, this.ListenNetwork))
+	}
+
+	return errs
+}
+func (this configuration) validateHTTPServer() (errs []error) {
+	for _, item := range []namedSetting{
+		{name: "MaxRequestHeaderSize", value: int64(this.MaxRequestHeaderSize)},
+		{name: "ReadRequestTimeout", value: int64(this.ReadRequestTimeout)},
+		{name: "ReadRequestHeaderTimeout", value: int64(this.ReadRequestHeaderTimeout)},
+		{name: "WriteResponseTimeout", value: int64(this.WriteResponseTimeout)},
+		{name: "IdleConnectionTimeout", value: int64(this.IdleConnectionTimeout)},
+	} {
+		errs = append(errs, item.validateNotNegative()...)
+	}
+
+	if this.ReadRequestTimeout > 0 && this.ReadRequestHeaderTimeout > this.ReadRequestTimeout {
+		errs = append(errs, fmt.Errorf("%w: ReadRequestHeaderTimeout (%s) exceeds ReadRequestTimeout (%s) which includes reading the headers",
+			ErrConflictingSetting, this.ReadRequestHeaderTimeout, this.ReadRequestTimeout))
+	}
+
+	return errs
+}
+func (this configuration) validateConnectionLimits() (errs []error) {
+	for _, item := range []namedSetting{
+		{name: "MaxConnections", value: int64(this.MaxConnections)},
+		{name: "MaxConnectionsPerClient", value: int64(this.MaxConnectionsPerClient)},
+		{name: "MaxConnectionAge", value: int64(this.MaxConnectionAge)},
+		{name: "MaxConnectionAgeJitter", value: int64(this.MaxConnectionAgeJitter)},
+		{name: "MaxConnectionRequests", value: int64(this.MaxConnectionRequests)},
+		{name: "MinRequestBodyRate", value: int64(this.MinRequestBodyRate)},
+		{name: "MinResponseRate", value: int64(this.MinResponseRate)},
+		{name: "MinTransferRateWindow", value: int64(this.MinTransferRateWindow)},
+	} {
+		errs = append(errs, item.validateNotNegative()...)
+	}
+
+	if this.ConnectionLimitIPv4Prefix < 0 || this.ConnectionLimitIPv4Prefix > 32 {
+		errs = append(errs, fmt.Errorf("%w: ConnectionLimitPrefix IPv4 prefix (%d) must be between 0 and 32", ErrInvalidSetting, this.ConnectionLimitIPv4Prefix))
+	}
+	if this.ConnectionLimitIPv6Prefix < 0 || this.ConnectionLimitIPv6Prefix > 128 {
+		errs = append(errs, fmt.Errorf("%w: ConnectionLimitPrefix IPv6 prefix (%d) must be between 0 and 128", ErrInvalidSetting, this.ConnectionLimitIPv6Prefix))
+	}
+	if this.ConnectionLimitBehavior > ConnectionLimitServiceUnavailable {
+		errs = append(errs, fmt.Errorf("%w: unknown ConnectionLimitBehavior (%d)", ErrInvalidSetting, this.ConnectionLimitBehavior))
+	}
+
+	if this.MaxConnectionAgeJitter > 0 && this.MaxConnectionAge == 0 {
+		errs = append(errs, fmt.Errorf("%w: MaxConnectionAgeJitter has no effect without MaxConnectionAge", ErrConflictingSetting))
+	}
+
+	if (this.MinRequestBodyRate > 0 || this.MinResponseRate > 0) && this.MinTransferRateWindow <= 0 {
+		errs = append(errs, fmt.Errorf("%w: MinTransferRateWindow must be positive when MinRequestBodyRate or MinResponseRate is set", ErrConflictingSetting))
+	}
+
+	if this.MaxConnections > 0 && this.MaxConnectionsPerClient > this.MaxConnections {
+		errs = append(errs, fmt.Errorf("%w: MaxConnectionsPerClient (%d) exceeds MaxConnections (%d)", ErrConflictingSetting, this.MaxConnectionsPerClient, this.MaxConnections))
+	}
+
+	return errs
+}
+func (this configuration) validateAccessLog() (errs []error) {
+	if this.AccessLogFormat > AccessLogJSON {
+		errs = append(errs, fmt.Errorf("%w: unknown AccessLogFormat (%d)", ErrInvalidSetting, this.AccessLogFormat))
+	}
+
+	for _, field := range this.AccessLogFields {
+		if !slices.Contains(accessLogFields, field) {
+			errs = append(errs, fmt.Errorf("%w: unknown AccessLogFields field [%s]", ErrInvalidSetting, field))
+		}
+	}
+
+	if this.AccessLogSampleRate < 0 || this.AccessLogSampleRate > 1 {
+		errs = append(errs, fmt.Errorf("%w: AccessLogSampleRate (%g) must be between 0 and 1", ErrInvalidSetting, this.AccessLogSampleRate))
+	}
+
+	errs = append(errs, namedSetting{name: "AccessLogSlowThreshold", value: int64(this.AccessLogSlowThreshold)}.validateNotNegative()...)
+
+	return errs
+}
+func (this configuration) validateServerLog() (errs []error) {
+	for _, class := range this.ServerLogSuppressed {
+		if class > ServerLogQuerySemicolon {
+			errs = append(errs, fmt.Errorf("%w: unknown SuppressServerLog class (%d)", ErrInvalidSetting, class))
+		}
+	}
+
+	errs = append(errs, namedSetting{name: "ServerLogRateLimit", value: int64(this.ServerLogRateLimit)}.validateNotNegative()...)
+
+	if this.ServerLogRateLimit > 0 && this.ServerLogRateInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: ServerLogRateLimit interval must be positive", ErrInvalidSetting))
+	}
+
+	return errs
+}
+func (this configuration) validatePanicStorm() (errs []error) {
+	for _, item := range []namedSetting{
+		{name: "PanicDeduplication", value: int64(this.PanicDeduplication)},
+		{name: "PanicStormThreshold", value: int64(this.PanicStormThreshold)},
+	} {
+		errs = append(errs, item.validateNotNegative()...)
+	}
+
+	if this.PanicStormThreshold > 0 && this.PanicStormInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: PanicStormThreshold interval must be positive", ErrInvalidSetting))
+	}
+	if this.PanicStormBehavior > PanicStormShutdown {
+		errs = append(errs, fmt.Errorf("%w: unknown PanicStormBehavior (%d)", ErrInvalidSetting, this.PanicStormBehavior))
+	}
+	if this.PanicStormHealth != nil && this.PanicStormThreshold == 0 {
+		errs = append(errs, fmt.Errorf("%w: PanicStormHealth has no effect without PanicStormThreshold", ErrConflictingSetting))
+	}
+
+	return errs
+}
+
+// validateIgnored reports the options provided by the caller, including those providing zero values such as
+// HandlePanic(false), which have no effect because a custom HTTPServer was provided.
+func (this configuration) validateIgnored(provided map[string]bool) (errs []error) {
+	if this.HTTPServer == nil {
+		return nil
+	}
+
+	for _, name := range []string{
+		"Handler",
+		"MaxRequestHeaderSize",
+		"ReadRequestTimeout",
+		"ReadRequestHeaderTimeout",
+		"WriteResponseTimeout",
+		"IdleConnectionTimeout",
+		"ErrorLogger",
+		"StructuredLogger",
+		"SuppressServerLog",
+		"ServerLogRateLimit",
+		"MaxConnectionAge",
+		"MaxConnectionAgeJitter",
+		"MaxConnectionRequests",
+		"MinRequestBodyRate",
+		"MinResponseRate",
+		"MinTransferRateWindow",
+		"HandlePanic",
+		"DumpRequestOnPanic",
+		"DumpRedactHeaders",
+		"DumpAllowHeaders",
+		"DumpRedactQuery",
+		"DumpRedactFields",
+		"DumpMaxBodySize",
+		"CaptureRequestBody",
+		"IgnoredErrors",
+		"PanicResponder",
+		"PanicClassifier",
+		"PanicDeduplication",
+		"PanicStormThreshold",
+		"AccessLogger",
+		"StructuredAccessLogger",
+		"TraceExporter",
+		"TraceSampleRate",
+		"RequestID",
+	} {
+		if provided[name] {
+			errs = append(errs, fmt.Errorf("%w: %s has no effect when a custom HTTPServer is provided", ErrConflictingSetting, name))
+		}
+	}
+
+	return errs
+}
+func (this configuration) validateRequestDump() (errs []error) {
+	errs = append(errs, namedSetting{name: "DumpMaxBodySize", value: int64(this.DumpMaxBodySize)}.validateNotNegative()...)
+
+	if this.CaptureRequestBody && this.DumpMaxBodySize == 0 {
+		errs = append(errs, fmt.Errorf("%w: CaptureRequestBody requires a DumpMaxBodySize limiting the size of the body retained", ErrConflictingSetting))
+	}
+
+	return errs
+}
+func (this configuration) validateRequestID() (errs []error) {
+	if !isHeaderName(this.RequestIDHeader) {
+		errs = append(errs, fmt.Errorf("%w: RequestIDHeader [%s] is not a valid header name", ErrInvalidSetting, this.RequestIDHeader))
+	}
+
+	if this.RequestIDMaxLength <= 0 {
+		errs = append(errs, fmt.Errorf("%w: RequestIDMaxLength (%d) must be positive", ErrInvalidSetting, this.RequestIDMaxLength))
+	}
+
+	return errs
+}
+
+// isHeaderName indicates whether the value is an RFC 9110 token, i.e. letters, digits and any of "!#$%&'*+-.^_`|~".
+func isHeaderName(value string) bool {
+	return len(value) > 0 && !strings.ContainsFunc(value, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+	})
+}
+
+type namedSetting struct {
+	name  string
+	value int64
+}
+
+func (this namedSetting) validateNotNegative() []error {
+	if this.value < 0 {
+		return []error{fmt.Errorf("%w: %s must not be negative", ErrInvalidSetting, this.name)}
+	}
+	return nil
+}
+
+var (
+	ErrInvalidSetting     = errors.New("invalid setting")
+	ErrConflictingSetting = errors.New("conflicting setting")
+)
@@ -0,0 +1,128 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/smarty/gunit"
+	"github.com/smarty/gunit/assert/should"
+)
+
+func TestValidateFixture(t *testing.T) {
+	gunit.Run(new(ValidateFixture), t)
+}
+
+type ValidateFixture struct {
+	*gunit.Fixture
+}
+
+func (this *ValidateFixture) TestDefaults_Valid() {
+	server, err := NewValidated()
+
+	this.So(err, should.BeNil)
+	this.So(server, should.NotBeNil)
+}
+func (this *ValidateFixture) TestValidListenAddresses_Valid() {
+	this.So(Validate(Options.ListenAddress("127.0.0.1:8080")), should.BeNil)
+	this.So(Validate(Options.ListenAddress("tcp6://[::1]:8443")), should.BeNil)
+	this.So(Validate(Options.ListenAddress("unix:///tmp/app.sock")), should.BeNil)
+	this.So(Validate(Options.ListenAddress("")), should.BeNil)
+}
+func (this *ValidateFixture) TestInvalidSettings_EverySettingReported() {
+	server, err := NewValidated(
+		Options.ListenAddress("my-listen-address"),
+		Options.ReadRequestTimeout(-time.Second),
+		Options.IdleConnectionTimeout(-time.Second),
+		Options.MaxRequestHeaderSize(-1),
+		Options.TLSConfig(&tls.Config{}),
+	)
+
+	this.So(server, should.BeNil)
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "my-listen-address")
+	this.So(err.Error(), should.ContainSubstring, "ReadRequestTimeout must not be negative")
+	this.So(err.Error(), should.ContainSubstring, "IdleConnectionTimeout must not be negative")
+	this.So(err.Error(), should.ContainSubstring, "MaxRequestHeaderSize must not be negative")
+	this.So(err.Error(), should.ContainSubstring, "TLSConfig has no Certificates")
+}
+func (this *ValidateFixture) TestUnsupportedNetworkOrPort_Invalid() {
+	this.So(errors.Is(Validate(Options.ListenAddress("udp://127.0.0.1:53")), ErrInvalidSetting), should.BeTrue)
+	this.So(errors.Is(Validate(Options.ListenAddress("127.0.0.1:not-a-port")), ErrInvalidSetting), should.BeTrue)
+}
+func (this *ValidateFixture) TestListenAddressesWithoutAddress_Invalid() {
+	for _, value := range []string{"localhost:8080", "tcp://", "unix://", "unix:app.sock", "foo://", "?backlog=16"} {
+		this.So(errors.Is(Validate(Options.ListenAddress(value)), ErrInvalidSetting), should.BeTrue)
+	}
+}
+func (this *ValidateFixture) TestListenAddressWithPathOrCredentials_Invalid() {
+	this.So(errors.Is(Validate(Options.ListenAddress("tcp://:8080/extra")), ErrInvalidSetting), should.BeTrue)
+	this.So(errors.Is(Validate(Options.ListenAddress("tcp://user@:8080")), ErrInvalidSetting), should.BeTrue)
+	this.So(Validate(Options.ListenAddress("tcp://:8080/")), should.BeNil)
+}
+func (this *ValidateFixture) TestConflictingTimeouts_Reported() {
+	err := Validate(
+		Options.ShutdownTimeout(time.Second),
+		Options.ForceShutdownTimeout(time.Minute),
+		Options.ReadRequestTimeout(time.Second),
+		Options.ReadRequestHeaderTimeout(time.Second*2),
+	)
+
+	this.So(errors.Is(err, ErrConflictingSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "ForceShutdownTimeout (1m0s) exceeds ShutdownTimeout (1s)")
+	this.So(err.Error(), should.ContainSubstring, "ReadRequestHeaderTimeout (2s) exceeds ReadRequestTimeout (1s)")
+}
+func (this *ValidateFixture) TestNilContext_Invalid() {
+	err := Validate(Options.Context(nil))
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+}
+func (this *ValidateFixture) TestCaptureRequestBodyWithoutLimit_Conflicting() {
+	err := Validate(Options.CaptureRequestBody(true), Options.DumpMaxBodySize(0))
+
+	this.So(errors.Is(err, ErrConflictingSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "CaptureRequestBody requires a DumpMaxBodySize")
+}
+func (this *ValidateFixture) TestInvalidRequestIDSettings_Reported() {
+	err := Validate(Options.RequestID(true), Options.RequestIDHeader("X-Request ID"), Options.RequestIDMaxLength(0))
+
+	this.So(errors.Is(err, ErrInvalidSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "RequestIDHeader [X-Request ID] is not a valid header name")
+	this.So(err.Error(), should.ContainSubstring, "RequestIDMaxLength (0) must be positive")
+}
+func (this *ValidateFixture) TestCustomHTTPServer_SettingsWithoutEffectReported() {
+	err := Validate(
+		Options.HTTPServer(&http.Server{}),
+		Options.Handler(http.NotFoundHandler()),
+		Options.WriteResponseTimeout(time.Second),
+		Options.DumpRequestOnPanic(true),
+		Options.SuppressServerLog(ServerLogTLSHandshake),
+		Options.ServerLogRateLimit(10, time.Minute),
+	)
+
+	this.So(errors.Is(err, ErrConflictingSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "Handler has no effect")
+	this.So(err.Error(), should.ContainSubstring, "WriteResponseTimeout has no effect")
+	this.So(err.Error(), should.ContainSubstring, "DumpRequestOnPanic has no effect")
+	this.So(err.Error(), should.ContainSubstring, "SuppressServerLog has no effect")
+	this.So(err.Error(), should.ContainSubstring, "ServerLogRateLimit has no effect")
+	this.So(err.Error(), should.NotContainSubstring, "ReadRequestTimeout")
+}
+func (this *ValidateFixture) TestCustomHTTPServerWithZeroValues_SettingsWithoutEffectReported() {
+	err := Validate(
+		Options.HTTPServer(&http.Server{}),
+		Options.HandlePanic(false),
+		Options.WriteResponseTimeout(0),
+		Options.PanicResponder(nil),
+	)
+
+	this.So(errors.Is(err, ErrConflictingSetting), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "HandlePanic has no effect")
+	this.So(err.Error(), should.ContainSubstring, "WriteResponseTimeout has no effect")
+	this.So(err.Error(), should.ContainSubstring, "PanicResponder has no effect")
+}
+func (this *ValidateFixture) TestCustomHTTPServerAlone_Valid() {
+	this.So(Validate(Options.HTTPServer(&http.Server{})), should.BeNil)
+}